@@ -2,16 +2,46 @@ package main
 
 import (
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/cert-manager/cert-manager/pkg/acme/webhook/cmd"
+	"github.com/ionos-cloud/cert-manager-webhook-ionos-cloud/internal/clouddns"
 	"github.com/ionos-cloud/cert-manager-webhook-ionos-cloud/internal/resolver"
+	"github.com/ionos-cloud/cert-manager-webhook-ionos-cloud/internal/vault"
 
 	"go.uber.org/zap"
 )
 
 var (
-	groupName = os.Getenv("GROUP_NAME")
-	namespace = os.Getenv("NAMESPACE")
+	groupName                   = os.Getenv("GROUP_NAME")
+	namespace                   = os.Getenv("NAMESPACE")
+	clusterResourceNamespace    = os.Getenv("CLUSTER_RESOURCE_NAMESPACE")
+	sweepTokens                 = os.Getenv("TOKEN_SWEEP_ENABLED") == "true"
+	secretInformer              = os.Getenv("SECRET_INFORMER_ENABLED") == "true"
+	secretInformerAllNamespaces = os.Getenv("SECRET_INFORMER_ALL_NAMESPACES") == "true"
+	secretAccessClusterWide     = os.Getenv("SECRET_ACCESS_CLUSTER_WIDE") == "true"
+	secretAccessNamespaces      = os.Getenv("SECRET_ACCESS_NAMESPACES")
+	secretLabelSelector         = os.Getenv("SECRET_LABEL_SELECTOR")
+	execPluginCommands          = os.Getenv("EXEC_PLUGIN_COMMANDS")
+	credentialsFileDirs         = os.Getenv("CREDENTIALS_FILE_DIRS")
+	allowedAPIURLs              = os.Getenv("ALLOWED_API_URLS")
+	zoneCreationAllowedSuffixes = os.Getenv("ZONE_CREATION_ALLOWED_SUFFIXES")
+	vaultAddress                = os.Getenv("VAULT_ADDR")
+	vaultRole                   = os.Getenv("VAULT_ROLE")
+	vaultAuthMount              = os.Getenv("VAULT_AUTH_MOUNT")
+	vaultCACert                 = os.Getenv("VAULT_CACERT")
+	vaultAllowedPaths           = os.Getenv("VAULT_ALLOWED_PATHS")
+	dnsAPIMaxRetries            = os.Getenv("DNS_API_MAX_RETRIES")
+	dnsAPIRetryInitialBackoff   = os.Getenv("DNS_API_RETRY_INITIAL_BACKOFF")
+	dnsAPIRetryMaxBackoff       = os.Getenv("DNS_API_RETRY_MAX_BACKOFF")
+	dnsAPIRateLimit             = os.Getenv("DNS_API_RATE_LIMIT")
+	dnsAPIRateLimitBurst        = os.Getenv("DNS_API_RATE_LIMIT_BURST")
+	recordProvisioningTimeout   = os.Getenv("RECORD_PROVISIONING_TIMEOUT")
+	recordDeletionTimeout       = os.Getenv("RECORD_DELETION_TIMEOUT")
+	zoneCacheTTL                = os.Getenv("ZONE_CACHE_TTL")
+	zoneCacheNegativeTTL        = os.Getenv("ZONE_CACHE_NEGATIVE_TTL")
 )
 
 func main() {
@@ -30,11 +60,110 @@ func main() {
 
 	logger.Info("Starting webhook server")
 
+	opts := []resolver.Option{
+		resolver.WithTokenSweep(sweepTokens),
+		resolver.WithClusterResourceNamespace(clusterResourceNamespace),
+	}
+	if secretAccessClusterWide || secretAccessNamespaces != "" {
+		opts = append(opts, resolver.WithSecretAccess(secretAccessClusterWide, strings.Split(secretAccessNamespaces, ",")))
+	}
+	if secretInformer {
+		opts = append(opts, resolver.WithSecretInformer(secretInformerAllNamespaces, secretLabelSelector))
+	}
+
+	if execPluginCommands != "" {
+		var commands [][]string
+		for _, command := range strings.Split(execPluginCommands, ",") {
+			commands = append(commands, strings.Fields(command))
+		}
+		opts = append(opts, resolver.WithExecPluginCommands(commands))
+	}
+	if credentialsFileDirs != "" {
+		opts = append(opts, resolver.WithCredentialsFileDirs(strings.Split(credentialsFileDirs, ",")))
+	}
+	if allowedAPIURLs != "" {
+		opts = append(opts, resolver.WithAllowedAPIURLs(strings.Split(allowedAPIURLs, ",")))
+	}
+	if zoneCreationAllowedSuffixes != "" {
+		opts = append(opts, resolver.WithZoneCreation(strings.Split(zoneCreationAllowedSuffixes, ",")))
+	}
+	if vaultAddress != "" {
+		vaultAPI, err := vault.CreateVaultAPI(vault.Config{
+			Address:    vaultAddress,
+			AuthMount:  vaultAuthMount,
+			Role:       vaultRole,
+			CACertFile: vaultCACert,
+		})
+		if err != nil {
+			panic(err)
+		}
+		opts = append(opts, resolver.WithVault(vaultAPI, strings.Split(vaultAllowedPaths, ",")))
+	}
+
+	retryPolicy := clouddns.DefaultRetryPolicy
+	if dnsAPIMaxRetries != "" {
+		if retryPolicy.MaxRetries, err = strconv.Atoi(dnsAPIMaxRetries); err != nil {
+			panic("DNS_API_MAX_RETRIES must be a number")
+		}
+	}
+	if dnsAPIRetryInitialBackoff != "" {
+		if retryPolicy.InitialBackoff, err = time.ParseDuration(dnsAPIRetryInitialBackoff); err != nil {
+			panic("DNS_API_RETRY_INITIAL_BACKOFF must be a duration")
+		}
+	}
+	if dnsAPIRetryMaxBackoff != "" {
+		if retryPolicy.MaxBackoff, err = time.ParseDuration(dnsAPIRetryMaxBackoff); err != nil {
+			panic("DNS_API_RETRY_MAX_BACKOFF must be a duration")
+		}
+	}
+
+	var rateLimitPolicy clouddns.RateLimitPolicy
+	if dnsAPIRateLimit != "" {
+		if rateLimitPolicy.RequestsPerSecond, err = strconv.ParseFloat(dnsAPIRateLimit, 64); err != nil {
+			panic("DNS_API_RATE_LIMIT must be a number")
+		}
+	}
+	if dnsAPIRateLimitBurst != "" {
+		if rateLimitPolicy.Burst, err = strconv.Atoi(dnsAPIRateLimitBurst); err != nil {
+			panic("DNS_API_RATE_LIMIT_BURST must be a number")
+		}
+	}
+
+	var provisioningTimeout, deletionTimeout time.Duration
+	if recordProvisioningTimeout != "" {
+		if provisioningTimeout, err = time.ParseDuration(recordProvisioningTimeout); err != nil {
+			panic("RECORD_PROVISIONING_TIMEOUT must be a duration")
+		}
+	}
+	if recordDeletionTimeout != "" {
+		if deletionTimeout, err = time.ParseDuration(recordDeletionTimeout); err != nil {
+			panic("RECORD_DELETION_TIMEOUT must be a duration")
+		}
+	}
+	opts = append(opts, resolver.WithRecordProvisioningWait(provisioningTimeout, deletionTimeout))
+
+	var zoneTTL, zoneNegativeTTL time.Duration
+	if zoneCacheTTL != "" {
+		if zoneTTL, err = time.ParseDuration(zoneCacheTTL); err != nil {
+			panic("ZONE_CACHE_TTL must be a duration")
+		}
+	}
+	if zoneCacheNegativeTTL != "" {
+		if zoneNegativeTTL, err = time.ParseDuration(zoneCacheNegativeTTL); err != nil {
+			panic("ZONE_CACHE_NEGATIVE_TTL must be a duration")
+		}
+	}
+	opts = append(opts, resolver.WithZoneCache(zoneTTL, zoneNegativeTTL))
+
 	// This will register our custom DNS provider with the webhook serving
 	// library, making it available as an API under the provided GroupName.
 	// You can register multiple DNS provider implementations with a single
 	// webhook, where the Name() method will be used to disambiguate between
 	// the different implementations.
-	cmd.RunWebhookServer(groupName, resolver.NewResolver(namespace,
-		resolver.DefaultK8FactoryFactory, resolver.DefaultDNSAPIFactory, resolver.DefaultGenerateTokenFunc, logger))
+	solver := resolver.NewResolver(namespace,
+		resolver.DefaultK8FactoryFactory, resolver.NewDNSAPIFactory(retryPolicy, clouddns.NewRateLimiters(rateLimitPolicy)),
+		resolver.DefaultAuthAPIFactory, logger, opts...)
+	cmd.RunWebhookServer(groupName, solver)
+	// The webhook server returns without waiting for the solver, wait until the generated tokens are deleted.
+	solver.(interface{ Shutdown() }).Shutdown()
 }
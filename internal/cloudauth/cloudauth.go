@@ -0,0 +1,61 @@
+package cloudauth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	authclient "github.com/ionos-cloud/sdk-go-auth"
+)
+
+type AuthAPI interface {
+	GenerateToken(ctx context.Context, ttl int32) (string, error)
+	GetTokens(ctx context.Context) ([]authclient.Token, error)
+	DeleteToken(ctx context.Context, tokenId string) error
+}
+
+func CreateAuthAPI(client *authclient.APIClient) AuthAPI {
+	return &APIClient{
+		client: client,
+	}
+}
+
+type APIClient struct {
+	client *authclient.APIClient
+}
+
+func (c *APIClient) GenerateToken(ctx context.Context, ttl int32) (string, error) {
+	jwtToken, _, err := c.client.TokensApi.TokensGenerate(ctx).Ttl(ttl).Execute()
+	if err != nil {
+		return "", fmt.Errorf("failed to obtain token from IONOS Cloud Auth API: %w", err)
+	}
+	if jwtToken.Token == nil || *jwtToken.Token == "" {
+		return "", fmt.Errorf("unexpected response from IONOS Cloud Auth API")
+	}
+	return *jwtToken.Token, nil
+}
+
+func (c *APIClient) GetTokens(ctx context.Context) ([]authclient.Token, error) {
+	tokens, resp, err := c.client.TokensApi.TokensGet(ctx).Execute()
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	if tokens.Tokens == nil {
+		return nil, nil
+	}
+	return *tokens.Tokens, nil
+}
+
+func (c *APIClient) DeleteToken(ctx context.Context, tokenId string) error {
+	_, resp, err := c.client.TokensApi.TokensDeleteById(ctx, tokenId).Execute()
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -2,86 +2,245 @@ package clouddns
 
 import (
 	"context"
-	"fmt"
 	"net/http"
+	"time"
 
 	dnsclient "github.com/ionos-cloud/sdk-go-dns"
 )
 
-const typeTxtRecord = "TXT"
-
 type DNSAPI interface {
-	GetZones(name string) (dnsclient.ZoneReadList, error)
-	CreateZone(name string) (dnsclient.ZoneRead, error)
-	GetRecords(zoneId string, name string) (dnsclient.RecordReadList, error)
-	CreateTXTRecord(zoneId string, recordName string, content string) (dnsclient.RecordRead, error)
-	DeleteRecord(zoneId string, recordId string) error
+	GetZones(ctx context.Context, name string) (dnsclient.ZoneReadList, error)
+	GetZone(ctx context.Context, zoneId string) (dnsclient.ZoneRead, error)
+	CreateZone(ctx context.Context, zone dnsclient.Zone) (dnsclient.ZoneRead, error)
+	DeleteZone(ctx context.Context, zoneId string) error
+	ListRecords(ctx context.Context, zoneId string, filter RecordFilter) (dnsclient.RecordReadList, error)
+	GetRecord(ctx context.Context, zoneId string, recordId string) (dnsclient.RecordRead, error)
+	CreateRecord(ctx context.Context, zoneId string, record dnsclient.Record) (dnsclient.RecordRead, error)
+	UpdateRecord(ctx context.Context, zoneId string, recordId string, record dnsclient.Record) (dnsclient.RecordRead, error)
+	UpsertRecord(ctx context.Context, zoneId string, record dnsclient.Record) (dnsclient.RecordRead, error)
+	DeleteRecord(ctx context.Context, zoneId string, recordId string) error
+}
+
+// RecordFilter selects the records of a zone by name and type. Empty fields match all records.
+type RecordFilter struct {
+	Name string
+	Type dnsclient.RecordType
 }
 
-func CreateDNSAPI(client *dnsclient.APIClient) DNSAPI {
+// CreateDNSAPI creates the DNS API from the SDK client. Failed calls are retried according to the retry policy,
+// instead of the retries of the SDK client, which are disabled. Every call, including retries, waits for the rate
+// limiter if one is given.
+func CreateDNSAPI(client *dnsclient.APIClient, retryPolicy RetryPolicy, rateLimiter *RateLimiter) DNSAPI {
+	client.GetConfig().MaxRetries = 1
 	return &APIClient{
-		client: client,
+		client:      client,
+		retryPolicy: retryPolicy,
+		rateLimiter: rateLimiter,
+		wait:        waitContext,
 	}
 }
 
 type APIClient struct {
-	client *dnsclient.APIClient
+	client      *dnsclient.APIClient
+	retryPolicy RetryPolicy
+	rateLimiter *RateLimiter
+	wait        func(ctx context.Context, d time.Duration) error
 }
 
-func (c *APIClient) GetZones(name string) (dnsclient.ZoneReadList, error) {
-	zoneList, resp, err := c.client.ZonesApi.ZonesGet(context.Background()).FilterZoneName(name).Execute()
+func (c *APIClient) GetZones(ctx context.Context, name string) (dnsclient.ZoneReadList, error) {
+	var zoneList dnsclient.ZoneReadList
+	items, err := listAll(ctx, c, "zones", func(offset, limit int32) (page []dnsclient.ZoneRead, links *dnsclient.Links,
+		resp *dnsclient.APIResponse, err error,
+	) {
+		var pageList dnsclient.ZoneReadList
+		pageList, resp, err = c.client.ZonesApi.ZonesGet(ctx).FilterZoneName(name).Offset(offset).Limit(limit).Execute()
+		if offset == 0 {
+			zoneList = pageList
+		}
+		return derefItems(pageList.Items), pageList.Links, resp, err
+	})
 	if err != nil {
 		return dnsclient.ZoneReadList{}, err
 	}
-	if resp.StatusCode != http.StatusOK {
-		return dnsclient.ZoneReadList{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
+	zoneList.Items = &items
 	return zoneList, nil
 }
 
-func (c *APIClient) CreateZone(name string) (dnsclient.ZoneRead, error) {
-	zoneCreate := *dnsclient.NewZoneCreate(*dnsclient.NewZone(name))
-	zone, resp, err := c.client.ZonesApi.ZonesPost(context.Background()).ZoneCreate(zoneCreate).Execute()
+func (c *APIClient) GetZone(ctx context.Context, zoneId string) (dnsclient.ZoneRead, error) {
+	var zone dnsclient.ZoneRead
+	resp, err := c.retry(ctx, true, func() (resp *dnsclient.APIResponse, err error) {
+		zone, resp, err = c.client.ZonesApi.ZonesFindById(ctx, zoneId).Execute()
+		return resp, err
+	})
 	if err != nil {
 		return dnsclient.ZoneRead{}, err
 	}
-	if resp.StatusCode != http.StatusCreated {
-		return dnsclient.ZoneRead{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	if resp.StatusCode != http.StatusOK {
+		return dnsclient.ZoneRead{}, unexpectedStatusError(resp)
 	}
 	return zone, nil
 }
 
-func (c *APIClient) GetRecords(zoneId string, name string) (dnsclient.RecordReadList, error) {
-	recordList, resp, err := c.client.RecordsApi.RecordsGet(context.Background()).FilterZoneId(zoneId).FilterName(name).
-		Execute()
+// CreateZone creates a zone. As creating is not idempotent, it is only retried when throttled.
+func (c *APIClient) CreateZone(ctx context.Context, zone dnsclient.Zone) (dnsclient.ZoneRead, error) {
+	zoneCreate := *dnsclient.NewZoneCreate(zone)
+	var created dnsclient.ZoneRead
+	resp, err := c.retry(ctx, false, func() (resp *dnsclient.APIResponse, err error) {
+		created, resp, err = c.client.ZonesApi.ZonesPost(ctx).ZoneCreate(zoneCreate).Execute()
+		return resp, err
+	})
+	if err != nil {
+		return dnsclient.ZoneRead{}, err
+	}
+	switch resp.StatusCode {
+	case http.StatusCreated, http.StatusAccepted:
+		return created, nil
+	default:
+		return dnsclient.ZoneRead{}, unexpectedStatusError(resp)
+	}
+}
+
+func (c *APIClient) DeleteZone(ctx context.Context, zoneId string) error {
+	attempts := 0
+	resp, err := c.retry(ctx, true, func() (resp *dnsclient.APIResponse, err error) {
+		attempts++
+		_, resp, err = c.client.ZonesApi.ZonesDelete(ctx, zoneId).Execute()
+		return resp, err
+	})
+	if err != nil {
+		// the zone may have been deleted by a previous attempt whose response was lost
+		if attempts > 1 && IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if resp.StatusCode != http.StatusAccepted {
+		return unexpectedStatusError(resp)
+	}
+	return nil
+}
+
+func (c *APIClient) ListRecords(ctx context.Context, zoneId string, filter RecordFilter) (dnsclient.RecordReadList,
+	error,
+) {
+	var recordList dnsclient.RecordReadList
+	items, err := listAll(ctx, c, "records", func(offset, limit int32) (page []dnsclient.RecordRead,
+		links *dnsclient.Links, resp *dnsclient.APIResponse, err error,
+	) {
+		var pageList dnsclient.RecordReadList
+		req := c.client.RecordsApi.RecordsGet(ctx).FilterZoneId(zoneId).Offset(offset).Limit(limit)
+		if filter.Name != "" {
+			req = req.FilterName(filter.Name)
+		}
+		if filter.Type != "" {
+			req = req.FilterType(filter.Type)
+		}
+		pageList, resp, err = req.Execute()
+		if offset == 0 {
+			recordList = pageList
+		}
+		return derefItems(pageList.Items), pageList.Links, resp, err
+	})
 	if err != nil {
 		return dnsclient.RecordReadList{}, err
 	}
+	recordList.Items = &items
+	return recordList, nil
+}
+
+func (c *APIClient) GetRecord(ctx context.Context, zoneId string, recordId string) (dnsclient.RecordRead, error) {
+	var record dnsclient.RecordRead
+	resp, err := c.retry(ctx, true, func() (resp *dnsclient.APIResponse, err error) {
+		record, resp, err = c.client.RecordsApi.ZonesRecordsFindById(ctx, zoneId, recordId).Execute()
+		return resp, err
+	})
+	if err != nil {
+		return dnsclient.RecordRead{}, err
+	}
 	if resp.StatusCode != http.StatusOK {
-		return dnsclient.RecordReadList{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return dnsclient.RecordRead{}, unexpectedStatusError(resp)
 	}
-	return recordList, nil
+	return record, nil
 }
 
-func (c *APIClient) CreateTXTRecord(zoneId string, recordName string, content string) (dnsclient.RecordRead, error) {
-	recordCreate := *dnsclient.NewRecordCreate(*dnsclient.NewRecord(recordName, typeTxtRecord, content)) // RecordCreate | record
-	record, resp, err := c.client.RecordsApi.ZonesRecordsPost(context.Background(), zoneId).RecordCreate(recordCreate).Execute()
+// CreateRecord creates a record in the zone. As creating is not idempotent, it is only retried when throttled.
+func (c *APIClient) CreateRecord(ctx context.Context, zoneId string, record dnsclient.Record) (dnsclient.RecordRead,
+	error,
+) {
+	recordCreate := *dnsclient.NewRecordCreate(record)
+	var created dnsclient.RecordRead
+	resp, err := c.retry(ctx, false, func() (resp *dnsclient.APIResponse, err error) {
+		created, resp, err = c.client.RecordsApi.ZonesRecordsPost(ctx, zoneId).RecordCreate(recordCreate).Execute()
+		return resp, err
+	})
 	if err != nil {
 		return dnsclient.RecordRead{}, err
 	}
 	if resp.StatusCode != http.StatusAccepted {
-		return dnsclient.RecordRead{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return dnsclient.RecordRead{}, unexpectedStatusError(resp)
 	}
-	return record, nil
+	return created, nil
+}
+
+// UpdateRecord replaces the record with the given ID, or creates it with this ID if it does not exist.
+func (c *APIClient) UpdateRecord(ctx context.Context, zoneId string, recordId string, record dnsclient.Record) (
+	dnsclient.RecordRead, error,
+) {
+	recordEnsure := *dnsclient.NewRecordEnsure(record)
+	var updated dnsclient.RecordRead
+	resp, err := c.retry(ctx, true, func() (resp *dnsclient.APIResponse, err error) {
+		updated, resp, err = c.client.RecordsApi.ZonesRecordsPut(ctx, zoneId, recordId).RecordEnsure(recordEnsure).Execute()
+		return resp, err
+	})
+	if err != nil {
+		return dnsclient.RecordRead{}, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusAccepted:
+		return updated, nil
+	default:
+		return dnsclient.RecordRead{}, unexpectedStatusError(resp)
+	}
+}
+
+// UpsertRecord updates the first record with the same name and type, or creates the record if there is none. It is
+// meant for the record types with a single record per name, e.g. CNAME.
+func (c *APIClient) UpsertRecord(ctx context.Context, zoneId string, record dnsclient.Record) (dnsclient.RecordRead,
+	error,
+) {
+	name, recordType := nameAndType(&record)
+	existing, err := c.ListRecords(ctx, zoneId, RecordFilter{Name: name, Type: recordType})
+	if err != nil {
+		return dnsclient.RecordRead{}, err
+	}
+	for _, r := range *existing.Items {
+		if r.Id == nil || r.Properties == nil {
+			continue
+		}
+		// the name filter of the API also matches partial names
+		if n, t := nameAndType(r.Properties); n == name && t == recordType {
+			return c.UpdateRecord(ctx, zoneId, *r.Id, record)
+		}
+	}
+	return c.CreateRecord(ctx, zoneId, record)
 }
 
-func (c *APIClient) DeleteRecord(zoneId string, recordId string) error {
-	_, resp, err := c.client.RecordsApi.ZonesRecordsDelete(context.Background(), zoneId, recordId).Execute()
+func (c *APIClient) DeleteRecord(ctx context.Context, zoneId string, recordId string) error {
+	attempts := 0
+	resp, err := c.retry(ctx, true, func() (resp *dnsclient.APIResponse, err error) {
+		attempts++
+		_, resp, err = c.client.RecordsApi.ZonesRecordsDelete(ctx, zoneId, recordId).Execute()
+		return resp, err
+	})
 	if err != nil {
+		// the record may have been deleted by a previous attempt whose response was lost
+		if attempts > 1 && IsNotFound(err) {
+			return nil
+		}
 		return err
 	}
 	if resp.StatusCode != http.StatusAccepted {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return unexpectedStatusError(resp)
 	}
 	return nil
 }
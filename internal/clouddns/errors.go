@@ -0,0 +1,135 @@
+package clouddns
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	dnsclient "github.com/ionos-cloud/sdk-go-dns"
+)
+
+// requestIDHeaders are the response headers which may carry the ID of a request, in order of preference.
+var requestIDHeaders = []string{"X-Request-Id", "X-Correlation-Id"}
+
+// APIError is an error response of the IONOS Cloud DNS API.
+type APIError struct {
+	StatusCode int
+	// Messages are the error messages returned by the API.
+	Messages []ErrorMessage
+	// RequestID identifies the request at IONOS Cloud, e.g. for support requests.
+	RequestID string
+}
+
+type ErrorMessage struct {
+	Code    string
+	Message string
+}
+
+func (e *APIError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "unexpected status code: %d", e.StatusCode)
+	for i, m := range e.Messages {
+		if i == 0 {
+			b.WriteString(": ")
+		} else {
+			b.WriteString("; ")
+		}
+		if m.Code != "" {
+			fmt.Fprintf(&b, "[%s] ", m.Code)
+		}
+		b.WriteString(m.Message)
+	}
+	if e.RequestID != "" {
+		fmt.Fprintf(&b, " (request ID %s)", e.RequestID)
+	}
+	return b.String()
+}
+
+// newAPIError converts the error of an SDK call into an APIError if the API returned an error response. Other
+// errors, e.g. network errors, are returned unchanged.
+func newAPIError(resp *dnsclient.APIResponse, err error) error {
+	if err == nil || resp == nil || resp.Response == nil {
+		return err
+	}
+	var openAPIError dnsclient.GenericOpenAPIError
+	if !errors.As(err, &openAPIError) || openAPIError.StatusCode() < http.StatusMultipleChoices {
+		return err
+	}
+	apiError := unexpectedStatusError(resp)
+	var body dnsclient.Error
+	if json.Unmarshal(openAPIError.Body(), &body) == nil && body.Messages != nil {
+		for _, m := range *body.Messages {
+			message := ErrorMessage{}
+			if m.ErrorCode != nil {
+				message.Code = *m.ErrorCode
+			}
+			if m.Message != nil {
+				message.Message = *m.Message
+			}
+			apiError.Messages = append(apiError.Messages, message)
+		}
+	}
+	return apiError
+}
+
+// unexpectedStatusError returns the APIError for a response with an unexpected status code.
+func unexpectedStatusError(resp *dnsclient.APIResponse) *APIError {
+	apiError := &APIError{StatusCode: resp.StatusCode}
+	for _, header := range requestIDHeaders {
+		if id := resp.Header.Get(header); id != "" {
+			apiError.RequestID = id
+			break
+		}
+	}
+	return apiError
+}
+
+func statusCodeOf(err error) int {
+	var apiError *APIError
+	if errors.As(err, &apiError) {
+		return apiError.StatusCode
+	}
+	return 0
+}
+
+// IsNotFound reports whether the requested resource does not exist.
+func IsNotFound(err error) bool {
+	return statusCodeOf(err) == http.StatusNotFound
+}
+
+// IsUnauthorized reports whether the API rejected the credentials, e.g. because the token expired.
+func IsUnauthorized(err error) bool {
+	return statusCodeOf(err) == http.StatusUnauthorized
+}
+
+// IsForbidden reports whether the credentials lack the permission for the call.
+func IsForbidden(err error) bool {
+	return statusCodeOf(err) == http.StatusForbidden
+}
+
+// IsThrottled reports whether the call was rejected by the rate limit of the API.
+func IsThrottled(err error) bool {
+	return statusCodeOf(err) == http.StatusTooManyRequests
+}
+
+// IsTransient reports whether the call may succeed when repeated: the call was throttled, failed with a server
+// error, or did not get a response at all.
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	switch statusCodeOf(err) {
+	case 0:
+		var openAPIError dnsclient.GenericOpenAPIError
+		// errors without a status code which are not network errors, e.g. decoding errors, are permanent
+		return !errors.As(err, &openAPIError)
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,106 @@
+//go:build unit
+
+package clouddns
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	dnsclient "github.com/ionos-cloud/sdk-go-dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIError(t *testing.T) {
+	testCases := []struct {
+		name             string
+		givenStatus      int
+		givenBody        string
+		givenHeader      string
+		thenErrorText    string
+		thenNotFound     bool
+		thenUnauthorized bool
+		thenForbidden    bool
+		thenThrottled    bool
+	}{
+		{
+			name:        "not found with messages and request id",
+			givenStatus: http.StatusNotFound,
+			givenBody: `{"httpStatus":404,"messages":[{"errorCode":"dns-404","message":"zone not found"},` +
+				`{"message":"check the zone id"}]}`,
+			givenHeader:   "X-Request-Id",
+			thenErrorText: "unexpected status code: 404: [dns-404] zone not found; check the zone id (request ID req-1)",
+			thenNotFound:  true,
+		},
+		{
+			name:             "unauthorized with correlation id",
+			givenStatus:      http.StatusUnauthorized,
+			givenBody:        `{"httpStatus":401,"messages":[{"errorCode":"paas-auth-1","message":"Unauthorized"}]}`,
+			givenHeader:      "X-Correlation-Id",
+			thenErrorText:    "unexpected status code: 401: [paas-auth-1] Unauthorized (request ID req-1)",
+			thenUnauthorized: true,
+		},
+		{
+			name:          "forbidden without body",
+			givenStatus:   http.StatusForbidden,
+			thenErrorText: "unexpected status code: 403",
+			thenForbidden: true,
+		},
+		{
+			name:          "throttled",
+			givenStatus:   http.StatusTooManyRequests,
+			givenBody:     `{"httpStatus":429,"messages":[{"message":"too many requests"}]}`,
+			thenErrorText: "unexpected status code: 429: too many requests",
+			thenThrottled: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tc.givenHeader != "" {
+					w.Header().Set(tc.givenHeader, "req-1")
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tc.givenStatus)
+				_, _ = w.Write([]byte(tc.givenBody))
+			}))
+			t.Cleanup(server.Close)
+			api := CreateDNSAPI(dnsclient.NewAPIClient(dnsclient.NewConfiguration("", "", "token", server.URL)),
+				RetryPolicy{}, nil).(*APIClient)
+
+			_, err := api.CreateZone(context.Background(), *dnsclient.NewZone("example.com"))
+
+			require.EqualError(t, err, tc.thenErrorText)
+			var apiError *APIError
+			require.True(t, errors.As(err, &apiError))
+			require.Equal(t, tc.givenStatus, apiError.StatusCode)
+			require.Equal(t, tc.thenNotFound, IsNotFound(err))
+			require.Equal(t, tc.thenUnauthorized, IsUnauthorized(err))
+			require.Equal(t, tc.thenForbidden, IsForbidden(err))
+			require.Equal(t, tc.thenThrottled, IsThrottled(err))
+		})
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	testCases := []struct {
+		name          string
+		givenErr      error
+		thenTransient bool
+	}{
+		{name: "no error", givenErr: nil, thenTransient: false},
+		{name: "throttled", givenErr: &APIError{StatusCode: http.StatusTooManyRequests}, thenTransient: true},
+		{name: "server error", givenErr: &APIError{StatusCode: http.StatusBadGateway}, thenTransient: true},
+		{name: "client error", givenErr: &APIError{StatusCode: http.StatusBadRequest}, thenTransient: false},
+		{name: "network error", givenErr: errors.New("connection reset by peer"), thenTransient: true},
+		{name: "canceled", givenErr: context.Canceled, thenTransient: false},
+		{name: "deadline exceeded", givenErr: context.DeadlineExceeded, thenTransient: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.thenTransient, IsTransient(tc.givenErr))
+		})
+	}
+}
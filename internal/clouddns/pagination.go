@@ -0,0 +1,52 @@
+package clouddns
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	dnsclient "github.com/ionos-cloud/sdk-go-dns"
+)
+
+const (
+	// pageLimit is the number of items requested per page of a list call.
+	pageLimit = 100
+	// maxPages bounds the number of pages read by a single list call.
+	maxPages = 100
+)
+
+// listAll reads all pages of a list call, following the offset/limit pagination of the API until there is no next
+// page. It fails instead of reading more than maxPages pages.
+func listAll[T any](ctx context.Context, c *APIClient, kind string,
+	fetch func(offset, limit int32) ([]T, *dnsclient.Links, *dnsclient.APIResponse, error),
+) ([]T, error) {
+	items := []T{}
+	for page := range maxPages {
+		var (
+			pageItems []T
+			links     *dnsclient.Links
+		)
+		resp, err := c.retry(ctx, true, func() (resp *dnsclient.APIResponse, err error) {
+			pageItems, links, resp, err = fetch(int32(page*pageLimit), pageLimit)
+			return resp, err
+		})
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, unexpectedStatusError(resp)
+		}
+		items = append(items, pageItems...)
+		if len(pageItems) == 0 || links == nil || links.Next == nil {
+			return items, nil
+		}
+	}
+	return nil, fmt.Errorf("more than %d pages of %s, giving up", maxPages, kind)
+}
+
+func derefItems[T any](items *[]T) []T {
+	if items == nil {
+		return nil
+	}
+	return *items
+}
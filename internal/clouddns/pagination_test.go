@@ -0,0 +1,77 @@
+//go:build unit
+
+package clouddns
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	dnsclient "github.com/ionos-cloud/sdk-go-dns"
+	"github.com/stretchr/testify/require"
+)
+
+// newPagedTestAPIClient serves total records, in pages of the requested limit. If endless is set, every page
+// links to a next page.
+func newPagedTestAPIClient(t *testing.T, total int, endless bool) (*APIClient, *[]string) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RawQuery)
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		items := []map[string]any{}
+		for i := offset; i < min(offset+limit, total); i++ {
+			items = append(items, map[string]any{"id": fmt.Sprintf("record-%d", i)})
+		}
+		links := map[string]string{}
+		if endless || offset+limit < total {
+			links["next"] = fmt.Sprintf("/records?offset=%d&limit=%d", offset+limit, limit)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]any{
+			"offset": offset, "limit": limit, "_links": links, "items": items,
+		}))
+	}))
+	t.Cleanup(server.Close)
+	api := CreateDNSAPI(dnsclient.NewAPIClient(dnsclient.NewConfiguration("", "", "token", server.URL)),
+		RetryPolicy{}, nil).(*APIClient)
+	return api, &requests
+}
+
+func TestListRecordsPagination(t *testing.T) {
+	testCases := []struct {
+		name         string
+		givenTotal   int
+		thenRequests int
+	}{
+		{name: "empty", givenTotal: 0, thenRequests: 1},
+		{name: "single page", givenTotal: 42, thenRequests: 1},
+		{name: "exactly one page", givenTotal: pageLimit, thenRequests: 1},
+		{name: "several pages", givenTotal: 2*pageLimit + 50, thenRequests: 3},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api, requests := newPagedTestAPIClient(t, tc.givenTotal, false)
+
+			recordList, err := api.ListRecords(context.Background(), "zone-id", RecordFilter{Name: "_acme-challenge"})
+			require.NoError(t, err)
+			require.Len(t, *recordList.Items, tc.givenTotal)
+			for i, record := range *recordList.Items {
+				require.Equal(t, fmt.Sprintf("record-%d", i), *record.Id)
+			}
+			require.Len(t, *requests, tc.thenRequests)
+		})
+	}
+}
+
+func TestGetZonesPaginationIsBounded(t *testing.T) {
+	api, requests := newPagedTestAPIClient(t, maxPages*pageLimit*2, true)
+
+	_, err := api.GetZones(context.Background(), "example.com")
+	require.EqualError(t, err, fmt.Sprintf("more than %d pages of zones, giving up", maxPages))
+	require.Len(t, *requests, maxPages)
+}
@@ -0,0 +1,69 @@
+package clouddns
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ionos-cloud/cert-manager-webhook-ionos-cloud/internal/metrics"
+	"golang.org/x/time/rate"
+)
+
+// RateLimitPolicy configures the client-side rate limit of the IONOS Cloud DNS API calls of an account: a token
+// bucket refilled with RequestsPerSecond and holding up to Burst tokens. A RequestsPerSecond of zero disables the
+// rate limit.
+type RateLimitPolicy struct {
+	RequestsPerSecond float64
+	Burst             int
+}
+
+// RateLimiters keeps a rate limiter per account, shared by all the DNS API clients of the account, so that the
+// calls of concurrent challenges are queued instead of being throttled by the API.
+type RateLimiters struct {
+	policy   RateLimitPolicy
+	mu       sync.Mutex
+	limiters map[string]*RateLimiter
+}
+
+func NewRateLimiters(policy RateLimitPolicy) *RateLimiters {
+	return &RateLimiters{
+		policy:   policy,
+		limiters: make(map[string]*RateLimiter),
+	}
+}
+
+// Get returns the rate limiter of the account, or nil if the rate limit is disabled.
+func (r *RateLimiters) Get(account string) *RateLimiter {
+	if r == nil || r.policy.RequestsPerSecond <= 0 {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	limiter, ok := r.limiters[account]
+	if !ok {
+		limiter = &RateLimiter{
+			limiter: rate.NewLimiter(rate.Limit(r.policy.RequestsPerSecond), max(r.policy.Burst, 1)),
+			account: account,
+		}
+		r.limiters[account] = limiter
+	}
+	return limiter
+}
+
+// RateLimiter limits the DNS API calls of an account.
+type RateLimiter struct {
+	limiter *rate.Limiter
+	account string
+}
+
+// wait blocks until the call is allowed by the rate limit. It fails if the context ends first, or if the wait
+// would exceed the deadline of the context. A nil rate limiter allows all calls.
+func (l *RateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	start := time.Now()
+	err := l.limiter.Wait(ctx)
+	metrics.DNSAPIRateLimitWaitSeconds.WithLabelValues(l.account).Observe(time.Since(start).Seconds())
+	return err
+}
@@ -0,0 +1,59 @@
+//go:build unit
+
+package clouddns
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ionos-cloud/cert-manager-webhook-ionos-cloud/internal/metrics"
+	dnsclient "github.com/ionos-cloud/sdk-go-dns"
+	"github.com/stretchr/testify/require"
+	"k8s.io/component-base/metrics/testutil"
+)
+
+func TestRateLimiters(t *testing.T) {
+	require.Nil(t, NewRateLimiters(RateLimitPolicy{}).Get("account"))
+	require.Nil(t, (*RateLimiters)(nil).Get("account"))
+
+	limiters := NewRateLimiters(RateLimitPolicy{RequestsPerSecond: 1, Burst: 1})
+	require.Same(t, limiters.Get("account"), limiters.Get("account"))
+	require.NotSame(t, limiters.Get("account"), limiters.Get("other-account"))
+}
+
+func TestRateLimitedCalls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"items":[]}`))
+	}))
+	t.Cleanup(server.Close)
+	limiters := NewRateLimiters(RateLimitPolicy{RequestsPerSecond: 20, Burst: 1})
+	newAPI := func() DNSAPI {
+		return CreateDNSAPI(dnsclient.NewAPIClient(dnsclient.NewConfiguration("", "", "token", server.URL)),
+			RetryPolicy{}, limiters.Get("rate-limited-account"))
+	}
+
+	// clients of the same account share the limiter
+	start := time.Now()
+	for _, api := range []DNSAPI{newAPI(), newAPI(), newAPI()} {
+		_, err := api.GetZones(context.Background(), "example.com")
+		require.NoError(t, err)
+	}
+	require.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+	count, err := testutil.GetHistogramMetricCount(metrics.DNSAPIRateLimitWaitSeconds.WithLabelValues("rate-limited-account"))
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), count)
+
+	// calls fail instead of waiting beyond their deadline
+	slow := CreateDNSAPI(dnsclient.NewAPIClient(dnsclient.NewConfiguration("", "", "token", server.URL)),
+		RetryPolicy{}, NewRateLimiters(RateLimitPolicy{RequestsPerSecond: 0.01, Burst: 1}).Get("slow-account"))
+	_, err = slow.GetZones(context.Background(), "example.com")
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err = slow.GetZones(ctx, "example.com")
+	require.ErrorContains(t, err, "would exceed context deadline")
+}
@@ -0,0 +1,45 @@
+package clouddns
+
+import (
+	"fmt"
+
+	dnsclient "github.com/ionos-cloud/sdk-go-dns"
+)
+
+// NewTXTRecord returns a TXT record with the given content.
+func NewTXTRecord(name, content string, ttl int32) dnsclient.Record {
+	return newRecord(name, dnsclient.RECORDTYPE_TXT, content, ttl)
+}
+
+// NewCNAMERecord returns a CNAME record pointing to the target.
+func NewCNAMERecord(name, target string, ttl int32) dnsclient.Record {
+	return newRecord(name, dnsclient.RECORDTYPE_CNAME, target, ttl)
+}
+
+// NewNSRecord returns an NS record delegating to the name server.
+func NewNSRecord(name, nameServer string, ttl int32) dnsclient.Record {
+	return newRecord(name, dnsclient.RECORDTYPE_NS, nameServer, ttl)
+}
+
+// NewCAARecord returns a CAA record, e.g. with the tag issue and the value letsencrypt.org.
+func NewCAARecord(name string, flags uint8, tag, value string, ttl int32) dnsclient.Record {
+	return newRecord(name, dnsclient.RECORDTYPE_CAA, fmt.Sprintf("%d %s %q", flags, tag, value), ttl)
+}
+
+func newRecord(name string, recordType dnsclient.RecordType, content string, ttl int32) dnsclient.Record {
+	record := dnsclient.NewRecord(name, recordType, content)
+	record.SetTtl(ttl)
+	return *record
+}
+
+func nameAndType(record *dnsclient.Record) (string, dnsclient.RecordType) {
+	var name string
+	var recordType dnsclient.RecordType
+	if record.Name != nil {
+		name = *record.Name
+	}
+	if record.Type != nil {
+		recordType = *record.Type
+	}
+	return name, recordType
+}
@@ -0,0 +1,154 @@
+//go:build unit
+
+package clouddns
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	dnsclient "github.com/ionos-cloud/sdk-go-dns"
+	"github.com/stretchr/testify/require"
+)
+
+type recordRequest struct {
+	method string
+	path   string
+	query  string
+	body   map[string]any
+}
+
+// newRecordsTestAPIClient serves the given existing records on list requests, and answers writes with the record
+// sent in the request.
+func newRecordsTestAPIClient(t *testing.T, existing []map[string]any) (*APIClient, *[]recordRequest) {
+	var requests []recordRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := recordRequest{method: r.Method, path: r.URL.Path, query: r.URL.RawQuery}
+		raw, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		if len(raw) > 0 {
+			require.NoError(t, json.Unmarshal(raw, &request.body))
+		}
+		requests = append(requests, request)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodGet:
+			if r.URL.Path == "/records" {
+				require.NoError(t, json.NewEncoder(w).Encode(map[string]any{"items": existing}))
+				return
+			}
+			require.NoError(t, json.NewEncoder(w).Encode(existing[0]))
+		case http.MethodPost:
+			w.WriteHeader(http.StatusAccepted)
+			require.NoError(t, json.NewEncoder(w).Encode(map[string]any{"id": "created-id", "properties": request.body["properties"]}))
+		case http.MethodPut:
+			require.NoError(t, json.NewEncoder(w).Encode(map[string]any{"id": "updated-id", "properties": request.body["properties"]}))
+		}
+	}))
+	t.Cleanup(server.Close)
+	api := CreateDNSAPI(dnsclient.NewAPIClient(dnsclient.NewConfiguration("", "", "token", server.URL)),
+		RetryPolicy{}, nil).(*APIClient)
+	return api, &requests
+}
+
+func TestRecords(t *testing.T) {
+	cname := map[string]any{
+		"id":         "record-id",
+		"properties": map[string]any{"name": "www", "type": "CNAME", "content": "example.com", "ttl": 3600},
+	}
+	partialName := map[string]any{
+		"id":         "other-id",
+		"properties": map[string]any{"name": "www.dev", "type": "CNAME", "content": "example.com", "ttl": 3600},
+	}
+	testCases := []struct {
+		name          string
+		givenExisting []map[string]any
+		whenCall      func(api *APIClient) (dnsclient.RecordRead, error)
+		thenRequests  []recordRequest
+		thenRecordId  string
+	}{
+		{
+			name: "create record",
+			whenCall: func(api *APIClient) (dnsclient.RecordRead, error) {
+				return api.CreateRecord(context.Background(), "zone-id", NewCAARecord("@", 0, "issue", "letsencrypt.org", 300))
+			},
+			thenRequests: []recordRequest{{
+				method: http.MethodPost, path: "/zones/zone-id/records",
+				body: map[string]any{"properties": map[string]any{
+					"name": "@", "type": "CAA", "content": `0 issue "letsencrypt.org"`, "ttl": float64(300), "enabled": true,
+				}},
+			}},
+			thenRecordId: "created-id",
+		},
+		{
+			name: "update record",
+			whenCall: func(api *APIClient) (dnsclient.RecordRead, error) {
+				return api.UpdateRecord(context.Background(), "zone-id", "record-id", NewNSRecord("sub", "ns1.example.com", 3600))
+			},
+			thenRequests: []recordRequest{{
+				method: http.MethodPut, path: "/zones/zone-id/records/record-id",
+				body: map[string]any{"properties": map[string]any{
+					"name": "sub", "type": "NS", "content": "ns1.example.com", "ttl": float64(3600), "enabled": true,
+				}},
+			}},
+			thenRecordId: "updated-id",
+		},
+		{
+			name:          "upsert updates the existing record",
+			givenExisting: []map[string]any{partialName, cname},
+			whenCall: func(api *APIClient) (dnsclient.RecordRead, error) {
+				return api.UpsertRecord(context.Background(), "zone-id", NewCNAMERecord("www", "example.org", 60))
+			},
+			thenRequests: []recordRequest{
+				{method: http.MethodGet, path: "/records", query: "filter.name=www&filter.type=CNAME&filter.zoneId=zone-id&limit=100&offset=0"},
+				{
+					method: http.MethodPut, path: "/zones/zone-id/records/record-id",
+					body: map[string]any{"properties": map[string]any{
+						"name": "www", "type": "CNAME", "content": "example.org", "ttl": float64(60), "enabled": true,
+					}},
+				},
+			},
+			thenRecordId: "updated-id",
+		},
+		{
+			name:          "upsert creates a missing record",
+			givenExisting: []map[string]any{partialName},
+			whenCall: func(api *APIClient) (dnsclient.RecordRead, error) {
+				return api.UpsertRecord(context.Background(), "zone-id", NewCNAMERecord("www", "example.org", 60))
+			},
+			thenRequests: []recordRequest{
+				{method: http.MethodGet, path: "/records", query: "filter.name=www&filter.type=CNAME&filter.zoneId=zone-id&limit=100&offset=0"},
+				{
+					method: http.MethodPost, path: "/zones/zone-id/records",
+					body: map[string]any{"properties": map[string]any{
+						"name": "www", "type": "CNAME", "content": "example.org", "ttl": float64(60), "enabled": true,
+					}},
+				},
+			},
+			thenRecordId: "created-id",
+		},
+		{
+			name:          "get record",
+			givenExisting: []map[string]any{cname},
+			whenCall: func(api *APIClient) (dnsclient.RecordRead, error) {
+				return api.GetRecord(context.Background(), "zone-id", "record-id")
+			},
+			thenRequests: []recordRequest{{method: http.MethodGet, path: "/zones/zone-id/records/record-id"}},
+			thenRecordId: "record-id",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api, requests := newRecordsTestAPIClient(t, tc.givenExisting)
+
+			record, err := tc.whenCall(api)
+
+			require.NoError(t, err)
+			require.Equal(t, tc.thenRecordId, *record.Id)
+			require.Equal(t, tc.thenRequests, *requests)
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package clouddns
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	dnsclient "github.com/ionos-cloud/sdk-go-dns"
+)
+
+// RetryPolicy configures how failed IONOS Cloud DNS API calls are retried. The backoff starts at InitialBackoff and
+// doubles with every retry up to MaxBackoff, with a random jitter. A Retry-After header of a 429 or 503 response
+// overrides the backoff.
+type RetryPolicy struct {
+	MaxRetries     int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries:     5,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     10 * time.Second,
+}
+
+// retry calls the operation until it succeeds, fails with an error which cannot be retried, or the retries are
+// exhausted. Idempotent operations are retried on network errors, throttling and server errors. Other operations
+// are only retried when throttled, as the request was then rejected without being processed.
+func (c *APIClient) retry(ctx context.Context, idempotent bool, operation func() (*dnsclient.APIResponse, error),
+) (*dnsclient.APIResponse, error) {
+	for attempt := 0; ; attempt++ {
+		if err := c.rateLimiter.wait(ctx); err != nil {
+			return nil, err
+		}
+		resp, err := operation()
+		err = newAPIError(resp, err)
+		if err == nil || attempt >= c.retryPolicy.MaxRetries || ctx.Err() != nil {
+			return resp, err
+		}
+		if !IsThrottled(err) && (!idempotent || !IsTransient(err)) {
+			return resp, err
+		}
+		backoff := c.retryPolicy.backoff(attempt)
+		if statusCode := statusCodeOf(err); statusCode == http.StatusTooManyRequests ||
+			statusCode == http.StatusServiceUnavailable {
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				backoff = retryAfter
+			}
+		}
+		if waitErr := c.wait(ctx, backoff); waitErr != nil {
+			return resp, errors.Join(err, waitErr)
+		}
+	}
+}
+
+// backoff returns the exponential backoff before the given retry, with equal jitter.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := p.InitialBackoff
+	for range attempt {
+		backoff *= 2
+		if backoff >= p.MaxBackoff {
+			backoff = p.MaxBackoff
+			break
+		}
+	}
+	if backoff <= 0 {
+		return 0
+	}
+	return backoff/2 + rand.N(backoff/2+1)
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+func waitContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
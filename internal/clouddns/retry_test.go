@@ -0,0 +1,199 @@
+//go:build unit
+
+package clouddns
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	dnsclient "github.com/ionos-cloud/sdk-go-dns"
+	"github.com/stretchr/testify/require"
+)
+
+type testResponse struct {
+	status     int
+	retryAfter string
+	body       string
+}
+
+func newTestAPIClient(t *testing.T, responses []testResponse) (*APIClient, *atomic.Int32, *[]time.Duration) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := responses[min(int(calls.Add(1))-1, len(responses)-1)]
+		if resp.retryAfter != "" {
+			w.Header().Set("Retry-After", resp.retryAfter)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(resp.status)
+		_, _ = w.Write([]byte(resp.body))
+	}))
+	t.Cleanup(server.Close)
+	api := CreateDNSAPI(dnsclient.NewAPIClient(dnsclient.NewConfiguration("", "", "token", server.URL)),
+		RetryPolicy{MaxRetries: 3, InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}, nil).(*APIClient)
+	var waits []time.Duration
+	api.wait = func(_ context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	return api, &calls, &waits
+}
+
+func TestRetry(t *testing.T) {
+	ok := testResponse{status: http.StatusOK, body: `{"items":[]}`}
+	accepted := testResponse{status: http.StatusAccepted, body: `{"id":"record-id"}`}
+	testCases := []struct {
+		name           string
+		givenCall      func(api *APIClient) error
+		givenResponses []testResponse
+		thenCalls      int32
+		thenWaits      []time.Duration
+		thenError      bool
+	}{
+		{
+			name: "get is retried on server errors",
+			givenCall: func(api *APIClient) error {
+				_, err := api.GetZones(context.Background(), "example.com")
+				return err
+			},
+			givenResponses: []testResponse{{status: http.StatusBadGateway}, {status: http.StatusInternalServerError}, ok},
+			thenCalls:      3,
+		},
+		{
+			name: "get zone is retried on server errors",
+			givenCall: func(api *APIClient) error {
+				_, err := api.GetZone(context.Background(), "zone-id")
+				return err
+			},
+			givenResponses: []testResponse{{status: http.StatusBadGateway}, {status: http.StatusOK, body: `{"id":"zone-id"}`}},
+			thenCalls:      2,
+		},
+		{
+			name: "retry after is honored on throttling",
+			givenCall: func(api *APIClient) error {
+				_, err := api.ListRecords(context.Background(), "zone-id", RecordFilter{Name: "_acme-challenge"})
+				return err
+			},
+			givenResponses: []testResponse{{status: http.StatusTooManyRequests, retryAfter: "7"}, ok},
+			thenCalls:      2,
+			thenWaits:      []time.Duration{7 * time.Second},
+		},
+		{
+			name: "retry after is honored on unavailability",
+			givenCall: func(api *APIClient) error {
+				_, err := api.ListRecords(context.Background(), "zone-id", RecordFilter{Name: "_acme-challenge"})
+				return err
+			},
+			givenResponses: []testResponse{{status: http.StatusServiceUnavailable, retryAfter: "2"}, ok},
+			thenCalls:      2,
+			thenWaits:      []time.Duration{2 * time.Second},
+		},
+		{
+			name: "retries are limited",
+			givenCall: func(api *APIClient) error {
+				_, err := api.GetZones(context.Background(), "example.com")
+				return err
+			},
+			givenResponses: []testResponse{{status: http.StatusServiceUnavailable}},
+			thenCalls:      4,
+			thenError:      true,
+		},
+		{
+			name: "create is not retried on server errors",
+			givenCall: func(api *APIClient) error {
+				_, err := api.CreateRecord(context.Background(), "zone-id", NewTXTRecord("_acme-challenge", "key", 60))
+				return err
+			},
+			givenResponses: []testResponse{{status: http.StatusServiceUnavailable}, accepted},
+			thenCalls:      1,
+			thenError:      true,
+		},
+		{
+			name: "create is retried on throttling",
+			givenCall: func(api *APIClient) error {
+				_, err := api.CreateRecord(context.Background(), "zone-id", NewTXTRecord("_acme-challenge", "key", 60))
+				return err
+			},
+			givenResponses: []testResponse{{status: http.StatusTooManyRequests, retryAfter: "1"}, accepted},
+			thenCalls:      2,
+			thenWaits:      []time.Duration{time.Second},
+		},
+		{
+			name: "client errors are not retried",
+			givenCall: func(api *APIClient) error {
+				_, err := api.GetZones(context.Background(), "example.com")
+				return err
+			},
+			givenResponses: []testResponse{{status: http.StatusUnauthorized}, ok},
+			thenCalls:      1,
+			thenError:      true,
+		},
+		{
+			name: "record deleted by a previous attempt",
+			givenCall: func(api *APIClient) error {
+				return api.DeleteRecord(context.Background(), "zone-id", "record-id")
+			},
+			givenResponses: []testResponse{{status: http.StatusGatewayTimeout}, {status: http.StatusNotFound}},
+			thenCalls:      2,
+		},
+		{
+			name: "zone deleted by a previous attempt",
+			givenCall: func(api *APIClient) error {
+				return api.DeleteZone(context.Background(), "zone-id")
+			},
+			givenResponses: []testResponse{{status: http.StatusBadGateway}, {status: http.StatusNotFound}},
+			thenCalls:      2,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api, calls, waits := newTestAPIClient(t, tc.givenResponses)
+			err := tc.givenCall(api)
+			if tc.thenError {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tc.thenCalls, calls.Load())
+			require.Len(t, *waits, int(tc.thenCalls)-1)
+			if tc.thenWaits != nil {
+				require.Equal(t, tc.thenWaits, *waits)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	for attempt, expected := range []time.Duration{
+		100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second,
+		time.Second,
+	} {
+		backoff := policy.backoff(attempt)
+		require.GreaterOrEqual(t, backoff, expected/2)
+		require.LessOrEqual(t, backoff, expected)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		value    string
+		thenOK   bool
+		thenWait time.Duration
+	}{
+		{value: "", thenOK: false},
+		{value: "120", thenOK: true, thenWait: 2 * time.Minute},
+		{value: "Thu, 01 Jan 2026 12:00:30 GMT", thenOK: true, thenWait: 30 * time.Second},
+		{value: "Thu, 01 Jan 2026 11:00:00 GMT", thenOK: true, thenWait: 0},
+		{value: "soon", thenOK: false},
+	}
+	for _, tc := range testCases {
+		wait, ok := parseRetryAfter(tc.value, now)
+		require.Equal(t, tc.thenOK, ok, tc.value)
+		require.Equal(t, tc.thenWait, wait, tc.value)
+	}
+}
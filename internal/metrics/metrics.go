@@ -0,0 +1,37 @@
+package metrics
+
+import (
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+// The metrics are registered in the legacy registry, which is served by the webhook server at /metrics.
+const namespace = "cert_manager_webhook_ionos_cloud"
+
+// AuthTokenDaysUntilExpiry is the number of days until the static auth token of a credentials source expires.
+var AuthTokenDaysUntilExpiry = metrics.NewGaugeVec(
+	&metrics.GaugeOpts{
+		Namespace:      namespace,
+		Name:           "auth_token_days_until_expiry",
+		Help:           "Number of days until the static IONOS Cloud auth token of a credentials source expires.",
+		StabilityLevel: metrics.ALPHA,
+	},
+	[]string{"source"},
+)
+
+// DNSAPIRateLimitWaitSeconds is the time DNS API calls of an account waited for the client-side rate limit.
+var DNSAPIRateLimitWaitSeconds = metrics.NewHistogramVec(
+	&metrics.HistogramOpts{
+		Namespace:      namespace,
+		Name:           "dns_api_rate_limit_wait_seconds",
+		Help:           "Time IONOS Cloud DNS API calls of an account waited for the client-side rate limit.",
+		Buckets:        []float64{0.001, 0.01, 0.1, 0.5, 1, 2.5, 5, 10, 30},
+		StabilityLevel: metrics.ALPHA,
+	},
+	[]string{"account"},
+)
+
+func init() {
+	legacyregistry.MustRegister(AuthTokenDaysUntilExpiry)
+	legacyregistry.MustRegister(DNSAPIRateLimitWaitSeconds)
+}
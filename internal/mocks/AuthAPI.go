@@ -0,0 +1,199 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	ionoscloud "github.com/ionos-cloud/sdk-go-auth"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// AuthAPI is an autogenerated mock type for the AuthAPI type
+type AuthAPI struct {
+	mock.Mock
+}
+
+type AuthAPI_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *AuthAPI) EXPECT() *AuthAPI_Expecter {
+	return &AuthAPI_Expecter{mock: &_m.Mock}
+}
+
+// DeleteToken provides a mock function with given fields: ctx, tokenId
+func (_m *AuthAPI) DeleteToken(ctx context.Context, tokenId string) error {
+	ret := _m.Called(ctx, tokenId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteToken")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, tokenId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// AuthAPI_DeleteToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteToken'
+type AuthAPI_DeleteToken_Call struct {
+	*mock.Call
+}
+
+// DeleteToken is a helper method to define mock.On call
+//   - ctx context.Context
+//   - tokenId string
+func (_e *AuthAPI_Expecter) DeleteToken(ctx interface{}, tokenId interface{}) *AuthAPI_DeleteToken_Call {
+	return &AuthAPI_DeleteToken_Call{Call: _e.mock.On("DeleteToken", ctx, tokenId)}
+}
+
+func (_c *AuthAPI_DeleteToken_Call) Run(run func(ctx context.Context, tokenId string)) *AuthAPI_DeleteToken_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *AuthAPI_DeleteToken_Call) Return(_a0 error) *AuthAPI_DeleteToken_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *AuthAPI_DeleteToken_Call) RunAndReturn(run func(context.Context, string) error) *AuthAPI_DeleteToken_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GenerateToken provides a mock function with given fields: ctx, ttl
+func (_m *AuthAPI) GenerateToken(ctx context.Context, ttl int32) (string, error) {
+	ret := _m.Called(ctx, ttl)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GenerateToken")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int32) (string, error)); ok {
+		return rf(ctx, ttl)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int32) string); ok {
+		r0 = rf(ctx, ttl)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int32) error); ok {
+		r1 = rf(ctx, ttl)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AuthAPI_GenerateToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GenerateToken'
+type AuthAPI_GenerateToken_Call struct {
+	*mock.Call
+}
+
+// GenerateToken is a helper method to define mock.On call
+//   - ctx context.Context
+//   - ttl int32
+func (_e *AuthAPI_Expecter) GenerateToken(ctx interface{}, ttl interface{}) *AuthAPI_GenerateToken_Call {
+	return &AuthAPI_GenerateToken_Call{Call: _e.mock.On("GenerateToken", ctx, ttl)}
+}
+
+func (_c *AuthAPI_GenerateToken_Call) Run(run func(ctx context.Context, ttl int32)) *AuthAPI_GenerateToken_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int32))
+	})
+	return _c
+}
+
+func (_c *AuthAPI_GenerateToken_Call) Return(_a0 string, _a1 error) *AuthAPI_GenerateToken_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *AuthAPI_GenerateToken_Call) RunAndReturn(run func(context.Context, int32) (string, error)) *AuthAPI_GenerateToken_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetTokens provides a mock function with given fields: ctx
+func (_m *AuthAPI) GetTokens(ctx context.Context) ([]ionoscloud.Token, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetTokens")
+	}
+
+	var r0 []ionoscloud.Token
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]ionoscloud.Token, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []ionoscloud.Token); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]ionoscloud.Token)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AuthAPI_GetTokens_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetTokens'
+type AuthAPI_GetTokens_Call struct {
+	*mock.Call
+}
+
+// GetTokens is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *AuthAPI_Expecter) GetTokens(ctx interface{}) *AuthAPI_GetTokens_Call {
+	return &AuthAPI_GetTokens_Call{Call: _e.mock.On("GetTokens", ctx)}
+}
+
+func (_c *AuthAPI_GetTokens_Call) Run(run func(ctx context.Context)) *AuthAPI_GetTokens_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *AuthAPI_GetTokens_Call) Return(_a0 []ionoscloud.Token, _a1 error) *AuthAPI_GetTokens_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *AuthAPI_GetTokens_Call) RunAndReturn(run func(context.Context) ([]ionoscloud.Token, error)) *AuthAPI_GetTokens_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewAuthAPI creates a new instance of AuthAPI. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewAuthAPI(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *AuthAPI {
+	mock := &AuthAPI{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
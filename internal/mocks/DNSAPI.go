@@ -3,7 +3,12 @@
 package mocks
 
 import (
+	context "context"
+
+	clouddns "github.com/ionos-cloud/cert-manager-webhook-ionos-cloud/internal/clouddns"
+
 	ionoscloud "github.com/ionos-cloud/sdk-go-dns"
+
 	mock "github.com/stretchr/testify/mock"
 )
 
@@ -20,27 +25,27 @@ func (_m *DNSAPI) EXPECT() *DNSAPI_Expecter {
 	return &DNSAPI_Expecter{mock: &_m.Mock}
 }
 
-// CreateTXTRecord provides a mock function with given fields: zoneId, recordName, content
-func (_m *DNSAPI) CreateTXTRecord(zoneId string, recordName string, content string) (ionoscloud.RecordRead, error) {
-	ret := _m.Called(zoneId, recordName, content)
+// CreateRecord provides a mock function with given fields: ctx, zoneId, record
+func (_m *DNSAPI) CreateRecord(ctx context.Context, zoneId string, record ionoscloud.Record) (ionoscloud.RecordRead, error) {
+	ret := _m.Called(ctx, zoneId, record)
 
 	if len(ret) == 0 {
-		panic("no return value specified for CreateTXTRecord")
+		panic("no return value specified for CreateRecord")
 	}
 
 	var r0 ionoscloud.RecordRead
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string, string, string) (ionoscloud.RecordRead, error)); ok {
-		return rf(zoneId, recordName, content)
+	if rf, ok := ret.Get(0).(func(context.Context, string, ionoscloud.Record) (ionoscloud.RecordRead, error)); ok {
+		return rf(ctx, zoneId, record)
 	}
-	if rf, ok := ret.Get(0).(func(string, string, string) ionoscloud.RecordRead); ok {
-		r0 = rf(zoneId, recordName, content)
+	if rf, ok := ret.Get(0).(func(context.Context, string, ionoscloud.Record) ionoscloud.RecordRead); ok {
+		r0 = rf(ctx, zoneId, record)
 	} else {
 		r0 = ret.Get(0).(ionoscloud.RecordRead)
 	}
 
-	if rf, ok := ret.Get(1).(func(string, string, string) error); ok {
-		r1 = rf(zoneId, recordName, content)
+	if rf, ok := ret.Get(1).(func(context.Context, string, ionoscloud.Record) error); ok {
+		r1 = rf(ctx, zoneId, record)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -48,39 +53,39 @@ func (_m *DNSAPI) CreateTXTRecord(zoneId string, recordName string, content stri
 	return r0, r1
 }
 
-// DNSAPI_CreateTXTRecord_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CreateTXTRecord'
-type DNSAPI_CreateTXTRecord_Call struct {
+// DNSAPI_CreateRecord_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CreateRecord'
+type DNSAPI_CreateRecord_Call struct {
 	*mock.Call
 }
 
-// CreateTXTRecord is a helper method to define mock.On call
+// CreateRecord is a helper method to define mock.On call
+//   - ctx context.Context
 //   - zoneId string
-//   - recordName string
-//   - content string
-func (_e *DNSAPI_Expecter) CreateTXTRecord(zoneId interface{}, recordName interface{}, content interface{}) *DNSAPI_CreateTXTRecord_Call {
-	return &DNSAPI_CreateTXTRecord_Call{Call: _e.mock.On("CreateTXTRecord", zoneId, recordName, content)}
+//   - record ionoscloud.Record
+func (_e *DNSAPI_Expecter) CreateRecord(ctx interface{}, zoneId interface{}, record interface{}) *DNSAPI_CreateRecord_Call {
+	return &DNSAPI_CreateRecord_Call{Call: _e.mock.On("CreateRecord", ctx, zoneId, record)}
 }
 
-func (_c *DNSAPI_CreateTXTRecord_Call) Run(run func(zoneId string, recordName string, content string)) *DNSAPI_CreateTXTRecord_Call {
+func (_c *DNSAPI_CreateRecord_Call) Run(run func(ctx context.Context, zoneId string, record ionoscloud.Record)) *DNSAPI_CreateRecord_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(string), args[1].(string), args[2].(string))
+		run(args[0].(context.Context), args[1].(string), args[2].(ionoscloud.Record))
 	})
 	return _c
 }
 
-func (_c *DNSAPI_CreateTXTRecord_Call) Return(_a0 ionoscloud.RecordRead, _a1 error) *DNSAPI_CreateTXTRecord_Call {
+func (_c *DNSAPI_CreateRecord_Call) Return(_a0 ionoscloud.RecordRead, _a1 error) *DNSAPI_CreateRecord_Call {
 	_c.Call.Return(_a0, _a1)
 	return _c
 }
 
-func (_c *DNSAPI_CreateTXTRecord_Call) RunAndReturn(run func(string, string, string) (ionoscloud.RecordRead, error)) *DNSAPI_CreateTXTRecord_Call {
+func (_c *DNSAPI_CreateRecord_Call) RunAndReturn(run func(context.Context, string, ionoscloud.Record) (ionoscloud.RecordRead, error)) *DNSAPI_CreateRecord_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// CreateZone provides a mock function with given fields: name
-func (_m *DNSAPI) CreateZone(name string) (ionoscloud.ZoneRead, error) {
-	ret := _m.Called(name)
+// CreateZone provides a mock function with given fields: ctx, zone
+func (_m *DNSAPI) CreateZone(ctx context.Context, zone ionoscloud.Zone) (ionoscloud.ZoneRead, error) {
+	ret := _m.Called(ctx, zone)
 
 	if len(ret) == 0 {
 		panic("no return value specified for CreateZone")
@@ -88,17 +93,17 @@ func (_m *DNSAPI) CreateZone(name string) (ionoscloud.ZoneRead, error) {
 
 	var r0 ionoscloud.ZoneRead
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string) (ionoscloud.ZoneRead, error)); ok {
-		return rf(name)
+	if rf, ok := ret.Get(0).(func(context.Context, ionoscloud.Zone) (ionoscloud.ZoneRead, error)); ok {
+		return rf(ctx, zone)
 	}
-	if rf, ok := ret.Get(0).(func(string) ionoscloud.ZoneRead); ok {
-		r0 = rf(name)
+	if rf, ok := ret.Get(0).(func(context.Context, ionoscloud.Zone) ionoscloud.ZoneRead); ok {
+		r0 = rf(ctx, zone)
 	} else {
 		r0 = ret.Get(0).(ionoscloud.ZoneRead)
 	}
 
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(name)
+	if rf, ok := ret.Get(1).(func(context.Context, ionoscloud.Zone) error); ok {
+		r1 = rf(ctx, zone)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -112,14 +117,15 @@ type DNSAPI_CreateZone_Call struct {
 }
 
 // CreateZone is a helper method to define mock.On call
-//   - name string
-func (_e *DNSAPI_Expecter) CreateZone(name interface{}) *DNSAPI_CreateZone_Call {
-	return &DNSAPI_CreateZone_Call{Call: _e.mock.On("CreateZone", name)}
+//   - ctx context.Context
+//   - zone ionoscloud.Zone
+func (_e *DNSAPI_Expecter) CreateZone(ctx interface{}, zone interface{}) *DNSAPI_CreateZone_Call {
+	return &DNSAPI_CreateZone_Call{Call: _e.mock.On("CreateZone", ctx, zone)}
 }
 
-func (_c *DNSAPI_CreateZone_Call) Run(run func(name string)) *DNSAPI_CreateZone_Call {
+func (_c *DNSAPI_CreateZone_Call) Run(run func(ctx context.Context, zone ionoscloud.Zone)) *DNSAPI_CreateZone_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(string))
+		run(args[0].(context.Context), args[1].(ionoscloud.Zone))
 	})
 	return _c
 }
@@ -129,22 +135,22 @@ func (_c *DNSAPI_CreateZone_Call) Return(_a0 ionoscloud.ZoneRead, _a1 error) *DN
 	return _c
 }
 
-func (_c *DNSAPI_CreateZone_Call) RunAndReturn(run func(string) (ionoscloud.ZoneRead, error)) *DNSAPI_CreateZone_Call {
+func (_c *DNSAPI_CreateZone_Call) RunAndReturn(run func(context.Context, ionoscloud.Zone) (ionoscloud.ZoneRead, error)) *DNSAPI_CreateZone_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// DeleteRecord provides a mock function with given fields: zoneId, recordId
-func (_m *DNSAPI) DeleteRecord(zoneId string, recordId string) error {
-	ret := _m.Called(zoneId, recordId)
+// DeleteRecord provides a mock function with given fields: ctx, zoneId, recordId
+func (_m *DNSAPI) DeleteRecord(ctx context.Context, zoneId string, recordId string) error {
+	ret := _m.Called(ctx, zoneId, recordId)
 
 	if len(ret) == 0 {
 		panic("no return value specified for DeleteRecord")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string, string) error); ok {
-		r0 = rf(zoneId, recordId)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, zoneId, recordId)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -158,15 +164,16 @@ type DNSAPI_DeleteRecord_Call struct {
 }
 
 // DeleteRecord is a helper method to define mock.On call
+//   - ctx context.Context
 //   - zoneId string
 //   - recordId string
-func (_e *DNSAPI_Expecter) DeleteRecord(zoneId interface{}, recordId interface{}) *DNSAPI_DeleteRecord_Call {
-	return &DNSAPI_DeleteRecord_Call{Call: _e.mock.On("DeleteRecord", zoneId, recordId)}
+func (_e *DNSAPI_Expecter) DeleteRecord(ctx interface{}, zoneId interface{}, recordId interface{}) *DNSAPI_DeleteRecord_Call {
+	return &DNSAPI_DeleteRecord_Call{Call: _e.mock.On("DeleteRecord", ctx, zoneId, recordId)}
 }
 
-func (_c *DNSAPI_DeleteRecord_Call) Run(run func(zoneId string, recordId string)) *DNSAPI_DeleteRecord_Call {
+func (_c *DNSAPI_DeleteRecord_Call) Run(run func(ctx context.Context, zoneId string, recordId string)) *DNSAPI_DeleteRecord_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(string), args[1].(string))
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
 	})
 	return _c
 }
@@ -176,32 +183,79 @@ func (_c *DNSAPI_DeleteRecord_Call) Return(_a0 error) *DNSAPI_DeleteRecord_Call
 	return _c
 }
 
-func (_c *DNSAPI_DeleteRecord_Call) RunAndReturn(run func(string, string) error) *DNSAPI_DeleteRecord_Call {
+func (_c *DNSAPI_DeleteRecord_Call) RunAndReturn(run func(context.Context, string, string) error) *DNSAPI_DeleteRecord_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// GetRecords provides a mock function with given fields: zoneId, name
-func (_m *DNSAPI) GetRecords(zoneId string, name string) (ionoscloud.RecordReadList, error) {
-	ret := _m.Called(zoneId, name)
+// DeleteZone provides a mock function with given fields: ctx, zoneId
+func (_m *DNSAPI) DeleteZone(ctx context.Context, zoneId string) error {
+	ret := _m.Called(ctx, zoneId)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetRecords")
+		panic("no return value specified for DeleteZone")
 	}
 
-	var r0 ionoscloud.RecordReadList
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, zoneId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DNSAPI_DeleteZone_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteZone'
+type DNSAPI_DeleteZone_Call struct {
+	*mock.Call
+}
+
+// DeleteZone is a helper method to define mock.On call
+//   - ctx context.Context
+//   - zoneId string
+func (_e *DNSAPI_Expecter) DeleteZone(ctx interface{}, zoneId interface{}) *DNSAPI_DeleteZone_Call {
+	return &DNSAPI_DeleteZone_Call{Call: _e.mock.On("DeleteZone", ctx, zoneId)}
+}
+
+func (_c *DNSAPI_DeleteZone_Call) Run(run func(ctx context.Context, zoneId string)) *DNSAPI_DeleteZone_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *DNSAPI_DeleteZone_Call) Return(_a0 error) *DNSAPI_DeleteZone_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DNSAPI_DeleteZone_Call) RunAndReturn(run func(context.Context, string) error) *DNSAPI_DeleteZone_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetRecord provides a mock function with given fields: ctx, zoneId, recordId
+func (_m *DNSAPI) GetRecord(ctx context.Context, zoneId string, recordId string) (ionoscloud.RecordRead, error) {
+	ret := _m.Called(ctx, zoneId, recordId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRecord")
+	}
+
+	var r0 ionoscloud.RecordRead
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string, string) (ionoscloud.RecordReadList, error)); ok {
-		return rf(zoneId, name)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (ionoscloud.RecordRead, error)); ok {
+		return rf(ctx, zoneId, recordId)
 	}
-	if rf, ok := ret.Get(0).(func(string, string) ionoscloud.RecordReadList); ok {
-		r0 = rf(zoneId, name)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) ionoscloud.RecordRead); ok {
+		r0 = rf(ctx, zoneId, recordId)
 	} else {
-		r0 = ret.Get(0).(ionoscloud.RecordReadList)
+		r0 = ret.Get(0).(ionoscloud.RecordRead)
 	}
 
-	if rf, ok := ret.Get(1).(func(string, string) error); ok {
-		r1 = rf(zoneId, name)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, zoneId, recordId)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -209,38 +263,96 @@ func (_m *DNSAPI) GetRecords(zoneId string, name string) (ionoscloud.RecordReadL
 	return r0, r1
 }
 
-// DNSAPI_GetRecords_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetRecords'
-type DNSAPI_GetRecords_Call struct {
+// DNSAPI_GetRecord_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetRecord'
+type DNSAPI_GetRecord_Call struct {
 	*mock.Call
 }
 
-// GetRecords is a helper method to define mock.On call
+// GetRecord is a helper method to define mock.On call
+//   - ctx context.Context
 //   - zoneId string
-//   - name string
-func (_e *DNSAPI_Expecter) GetRecords(zoneId interface{}, name interface{}) *DNSAPI_GetRecords_Call {
-	return &DNSAPI_GetRecords_Call{Call: _e.mock.On("GetRecords", zoneId, name)}
+//   - recordId string
+func (_e *DNSAPI_Expecter) GetRecord(ctx interface{}, zoneId interface{}, recordId interface{}) *DNSAPI_GetRecord_Call {
+	return &DNSAPI_GetRecord_Call{Call: _e.mock.On("GetRecord", ctx, zoneId, recordId)}
 }
 
-func (_c *DNSAPI_GetRecords_Call) Run(run func(zoneId string, name string)) *DNSAPI_GetRecords_Call {
+func (_c *DNSAPI_GetRecord_Call) Run(run func(ctx context.Context, zoneId string, recordId string)) *DNSAPI_GetRecord_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(string), args[1].(string))
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
 	})
 	return _c
 }
 
-func (_c *DNSAPI_GetRecords_Call) Return(_a0 ionoscloud.RecordReadList, _a1 error) *DNSAPI_GetRecords_Call {
+func (_c *DNSAPI_GetRecord_Call) Return(_a0 ionoscloud.RecordRead, _a1 error) *DNSAPI_GetRecord_Call {
 	_c.Call.Return(_a0, _a1)
 	return _c
 }
 
-func (_c *DNSAPI_GetRecords_Call) RunAndReturn(run func(string, string) (ionoscloud.RecordReadList, error)) *DNSAPI_GetRecords_Call {
+func (_c *DNSAPI_GetRecord_Call) RunAndReturn(run func(context.Context, string, string) (ionoscloud.RecordRead, error)) *DNSAPI_GetRecord_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// GetZones provides a mock function with given fields: name
-func (_m *DNSAPI) GetZones(name string) (ionoscloud.ZoneReadList, error) {
-	ret := _m.Called(name)
+// GetZone provides a mock function with given fields: ctx, zoneId
+func (_m *DNSAPI) GetZone(ctx context.Context, zoneId string) (ionoscloud.ZoneRead, error) {
+	ret := _m.Called(ctx, zoneId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetZone")
+	}
+
+	var r0 ionoscloud.ZoneRead
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (ionoscloud.ZoneRead, error)); ok {
+		return rf(ctx, zoneId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) ionoscloud.ZoneRead); ok {
+		r0 = rf(ctx, zoneId)
+	} else {
+		r0 = ret.Get(0).(ionoscloud.ZoneRead)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, zoneId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DNSAPI_GetZone_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetZone'
+type DNSAPI_GetZone_Call struct {
+	*mock.Call
+}
+
+// GetZone is a helper method to define mock.On call
+//   - ctx context.Context
+//   - zoneId string
+func (_e *DNSAPI_Expecter) GetZone(ctx interface{}, zoneId interface{}) *DNSAPI_GetZone_Call {
+	return &DNSAPI_GetZone_Call{Call: _e.mock.On("GetZone", ctx, zoneId)}
+}
+
+func (_c *DNSAPI_GetZone_Call) Run(run func(ctx context.Context, zoneId string)) *DNSAPI_GetZone_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *DNSAPI_GetZone_Call) Return(_a0 ionoscloud.ZoneRead, _a1 error) *DNSAPI_GetZone_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DNSAPI_GetZone_Call) RunAndReturn(run func(context.Context, string) (ionoscloud.ZoneRead, error)) *DNSAPI_GetZone_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetZones provides a mock function with given fields: ctx, name
+func (_m *DNSAPI) GetZones(ctx context.Context, name string) (ionoscloud.ZoneReadList, error) {
+	ret := _m.Called(ctx, name)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetZones")
@@ -248,17 +360,17 @@ func (_m *DNSAPI) GetZones(name string) (ionoscloud.ZoneReadList, error) {
 
 	var r0 ionoscloud.ZoneReadList
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string) (ionoscloud.ZoneReadList, error)); ok {
-		return rf(name)
+	if rf, ok := ret.Get(0).(func(context.Context, string) (ionoscloud.ZoneReadList, error)); ok {
+		return rf(ctx, name)
 	}
-	if rf, ok := ret.Get(0).(func(string) ionoscloud.ZoneReadList); ok {
-		r0 = rf(name)
+	if rf, ok := ret.Get(0).(func(context.Context, string) ionoscloud.ZoneReadList); ok {
+		r0 = rf(ctx, name)
 	} else {
 		r0 = ret.Get(0).(ionoscloud.ZoneReadList)
 	}
 
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(name)
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, name)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -272,14 +384,15 @@ type DNSAPI_GetZones_Call struct {
 }
 
 // GetZones is a helper method to define mock.On call
+//   - ctx context.Context
 //   - name string
-func (_e *DNSAPI_Expecter) GetZones(name interface{}) *DNSAPI_GetZones_Call {
-	return &DNSAPI_GetZones_Call{Call: _e.mock.On("GetZones", name)}
+func (_e *DNSAPI_Expecter) GetZones(ctx interface{}, name interface{}) *DNSAPI_GetZones_Call {
+	return &DNSAPI_GetZones_Call{Call: _e.mock.On("GetZones", ctx, name)}
 }
 
-func (_c *DNSAPI_GetZones_Call) Run(run func(name string)) *DNSAPI_GetZones_Call {
+func (_c *DNSAPI_GetZones_Call) Run(run func(ctx context.Context, name string)) *DNSAPI_GetZones_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(string))
+		run(args[0].(context.Context), args[1].(string))
 	})
 	return _c
 }
@@ -289,7 +402,182 @@ func (_c *DNSAPI_GetZones_Call) Return(_a0 ionoscloud.ZoneReadList, _a1 error) *
 	return _c
 }
 
-func (_c *DNSAPI_GetZones_Call) RunAndReturn(run func(string) (ionoscloud.ZoneReadList, error)) *DNSAPI_GetZones_Call {
+func (_c *DNSAPI_GetZones_Call) RunAndReturn(run func(context.Context, string) (ionoscloud.ZoneReadList, error)) *DNSAPI_GetZones_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListRecords provides a mock function with given fields: ctx, zoneId, filter
+func (_m *DNSAPI) ListRecords(ctx context.Context, zoneId string, filter clouddns.RecordFilter) (ionoscloud.RecordReadList, error) {
+	ret := _m.Called(ctx, zoneId, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListRecords")
+	}
+
+	var r0 ionoscloud.RecordReadList
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, clouddns.RecordFilter) (ionoscloud.RecordReadList, error)); ok {
+		return rf(ctx, zoneId, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, clouddns.RecordFilter) ionoscloud.RecordReadList); ok {
+		r0 = rf(ctx, zoneId, filter)
+	} else {
+		r0 = ret.Get(0).(ionoscloud.RecordReadList)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, clouddns.RecordFilter) error); ok {
+		r1 = rf(ctx, zoneId, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DNSAPI_ListRecords_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListRecords'
+type DNSAPI_ListRecords_Call struct {
+	*mock.Call
+}
+
+// ListRecords is a helper method to define mock.On call
+//   - ctx context.Context
+//   - zoneId string
+//   - filter clouddns.RecordFilter
+func (_e *DNSAPI_Expecter) ListRecords(ctx interface{}, zoneId interface{}, filter interface{}) *DNSAPI_ListRecords_Call {
+	return &DNSAPI_ListRecords_Call{Call: _e.mock.On("ListRecords", ctx, zoneId, filter)}
+}
+
+func (_c *DNSAPI_ListRecords_Call) Run(run func(ctx context.Context, zoneId string, filter clouddns.RecordFilter)) *DNSAPI_ListRecords_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(clouddns.RecordFilter))
+	})
+	return _c
+}
+
+func (_c *DNSAPI_ListRecords_Call) Return(_a0 ionoscloud.RecordReadList, _a1 error) *DNSAPI_ListRecords_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DNSAPI_ListRecords_Call) RunAndReturn(run func(context.Context, string, clouddns.RecordFilter) (ionoscloud.RecordReadList, error)) *DNSAPI_ListRecords_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateRecord provides a mock function with given fields: ctx, zoneId, recordId, record
+func (_m *DNSAPI) UpdateRecord(ctx context.Context, zoneId string, recordId string, record ionoscloud.Record) (ionoscloud.RecordRead, error) {
+	ret := _m.Called(ctx, zoneId, recordId, record)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateRecord")
+	}
+
+	var r0 ionoscloud.RecordRead
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, ionoscloud.Record) (ionoscloud.RecordRead, error)); ok {
+		return rf(ctx, zoneId, recordId, record)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, ionoscloud.Record) ionoscloud.RecordRead); ok {
+		r0 = rf(ctx, zoneId, recordId, record)
+	} else {
+		r0 = ret.Get(0).(ionoscloud.RecordRead)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, ionoscloud.Record) error); ok {
+		r1 = rf(ctx, zoneId, recordId, record)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DNSAPI_UpdateRecord_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateRecord'
+type DNSAPI_UpdateRecord_Call struct {
+	*mock.Call
+}
+
+// UpdateRecord is a helper method to define mock.On call
+//   - ctx context.Context
+//   - zoneId string
+//   - recordId string
+//   - record ionoscloud.Record
+func (_e *DNSAPI_Expecter) UpdateRecord(ctx interface{}, zoneId interface{}, recordId interface{}, record interface{}) *DNSAPI_UpdateRecord_Call {
+	return &DNSAPI_UpdateRecord_Call{Call: _e.mock.On("UpdateRecord", ctx, zoneId, recordId, record)}
+}
+
+func (_c *DNSAPI_UpdateRecord_Call) Run(run func(ctx context.Context, zoneId string, recordId string, record ionoscloud.Record)) *DNSAPI_UpdateRecord_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(ionoscloud.Record))
+	})
+	return _c
+}
+
+func (_c *DNSAPI_UpdateRecord_Call) Return(_a0 ionoscloud.RecordRead, _a1 error) *DNSAPI_UpdateRecord_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DNSAPI_UpdateRecord_Call) RunAndReturn(run func(context.Context, string, string, ionoscloud.Record) (ionoscloud.RecordRead, error)) *DNSAPI_UpdateRecord_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpsertRecord provides a mock function with given fields: ctx, zoneId, record
+func (_m *DNSAPI) UpsertRecord(ctx context.Context, zoneId string, record ionoscloud.Record) (ionoscloud.RecordRead, error) {
+	ret := _m.Called(ctx, zoneId, record)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpsertRecord")
+	}
+
+	var r0 ionoscloud.RecordRead
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, ionoscloud.Record) (ionoscloud.RecordRead, error)); ok {
+		return rf(ctx, zoneId, record)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, ionoscloud.Record) ionoscloud.RecordRead); ok {
+		r0 = rf(ctx, zoneId, record)
+	} else {
+		r0 = ret.Get(0).(ionoscloud.RecordRead)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, ionoscloud.Record) error); ok {
+		r1 = rf(ctx, zoneId, record)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DNSAPI_UpsertRecord_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpsertRecord'
+type DNSAPI_UpsertRecord_Call struct {
+	*mock.Call
+}
+
+// UpsertRecord is a helper method to define mock.On call
+//   - ctx context.Context
+//   - zoneId string
+//   - record ionoscloud.Record
+func (_e *DNSAPI_Expecter) UpsertRecord(ctx interface{}, zoneId interface{}, record interface{}) *DNSAPI_UpsertRecord_Call {
+	return &DNSAPI_UpsertRecord_Call{Call: _e.mock.On("UpsertRecord", ctx, zoneId, record)}
+}
+
+func (_c *DNSAPI_UpsertRecord_Call) Run(run func(ctx context.Context, zoneId string, record ionoscloud.Record)) *DNSAPI_UpsertRecord_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(ionoscloud.Record))
+	})
+	return _c
+}
+
+func (_c *DNSAPI_UpsertRecord_Call) Return(_a0 ionoscloud.RecordRead, _a1 error) *DNSAPI_UpsertRecord_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DNSAPI_UpsertRecord_Call) RunAndReturn(run func(context.Context, string, ionoscloud.Record) (ionoscloud.RecordRead, error)) *DNSAPI_UpsertRecord_Call {
 	_c.Call.Return(run)
 	return _c
 }
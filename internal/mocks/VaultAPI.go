@@ -0,0 +1,98 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	vault "github.com/ionos-cloud/cert-manager-webhook-ionos-cloud/internal/vault"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// VaultAPI is an autogenerated mock type for the VaultAPI type
+type VaultAPI struct {
+	mock.Mock
+}
+
+type VaultAPI_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *VaultAPI) EXPECT() *VaultAPI_Expecter {
+	return &VaultAPI_Expecter{mock: &_m.Mock}
+}
+
+// ReadSecret provides a mock function with given fields: ctx, mount, path, kvVersion
+func (_m *VaultAPI) ReadSecret(ctx context.Context, mount string, path string, kvVersion int) (*vault.Secret, error) {
+	ret := _m.Called(ctx, mount, path, kvVersion)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReadSecret")
+	}
+
+	var r0 *vault.Secret
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int) (*vault.Secret, error)); ok {
+		return rf(ctx, mount, path, kvVersion)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int) *vault.Secret); ok {
+		r0 = rf(ctx, mount, path, kvVersion)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*vault.Secret)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, int) error); ok {
+		r1 = rf(ctx, mount, path, kvVersion)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// VaultAPI_ReadSecret_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ReadSecret'
+type VaultAPI_ReadSecret_Call struct {
+	*mock.Call
+}
+
+// ReadSecret is a helper method to define mock.On call
+//   - ctx context.Context
+//   - mount string
+//   - path string
+//   - kvVersion int
+func (_e *VaultAPI_Expecter) ReadSecret(ctx interface{}, mount interface{}, path interface{}, kvVersion interface{}) *VaultAPI_ReadSecret_Call {
+	return &VaultAPI_ReadSecret_Call{Call: _e.mock.On("ReadSecret", ctx, mount, path, kvVersion)}
+}
+
+func (_c *VaultAPI_ReadSecret_Call) Run(run func(ctx context.Context, mount string, path string, kvVersion int)) *VaultAPI_ReadSecret_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(int))
+	})
+	return _c
+}
+
+func (_c *VaultAPI_ReadSecret_Call) Return(_a0 *vault.Secret, _a1 error) *VaultAPI_ReadSecret_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *VaultAPI_ReadSecret_Call) RunAndReturn(run func(context.Context, string, string, int) (*vault.Secret, error)) *VaultAPI_ReadSecret_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewVaultAPI creates a new instance of VaultAPI. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewVaultAPI(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *VaultAPI {
+	mock := &VaultAPI{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,236 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/ionos-cloud/cert-manager-webhook-ionos-cloud/internal/metrics"
+	ionoscloud_auth "github.com/ionos-cloud/sdk-go-auth"
+	"go.uber.org/zap"
+)
+
+const (
+	ambientTokenFileEnvVar    = "IONOS_TOKEN_FILE"
+	ambientUsernameFileEnvVar = "IONOS_USERNAME_FILE"
+	ambientPasswordFileEnvVar = "IONOS_PASSWORD_FILE"
+	ambientCredentialsSource  = "ambient"
+	// tokenExpiryWarningPeriod is how long before its expiry a warning is logged for a static token.
+	tokenExpiryWarningPeriod = 14 * 24 * time.Hour
+)
+
+// credentials are the IONOS Cloud credentials used to solve a challenge. Either the token or the username and
+// password are set.
+type credentials struct {
+	// source identifies where the credentials come from, e.g. the namespace/name of a secret.
+	source string
+	// version changes when the credentials in the source change.
+	version  string
+	token    string
+	username string
+	password string
+	// exec is set if the token is obtained from a credential plugin.
+	exec *execConfig
+}
+
+// credentialsConfigFor selects the credentials for the challenge. The first entry of the credentials list with a
+// domain matching the resolved zone or the DNS name of the challenge is used, or otherwise the first entry without
+// domains. If the list is empty, the credentials configured at the top level are used.
+func credentialsConfigFor(ch *v1alpha1.ChallengeRequest, config ionosCloudDNS01SolverConfig) (credentialsConfig, error) {
+	if len(config.Credentials) == 0 {
+		return config.credentialsConfig, nil
+	}
+	names := []string{
+		strings.ToLower(zoneNameFromChallenge(ch)),
+		strings.ToLower(strings.TrimPrefix(strings.TrimSuffix(ch.DNSName, "."), "*.")),
+	}
+	for _, entry := range config.Credentials {
+		for _, domain := range entry.Domains {
+			domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+			for _, name := range names {
+				if name == domain || strings.HasSuffix(name, "."+domain) {
+					return entry.credentialsConfig, nil
+				}
+			}
+		}
+	}
+	for _, entry := range config.Credentials {
+		if len(entry.Domains) == 0 {
+			return entry.credentialsConfig, nil
+		}
+	}
+	return credentialsConfig{}, fmt.Errorf("no credentials configured for domain '%s'", zoneNameFromChallenge(ch))
+}
+
+// resolveCredentials returns the credentials for the challenge. A credential plugin, credentials files or a Vault
+// secret configured in the solver config are used instead of a secret. If the solver config does not reference a
+// secret and cert-manager allows ambient credentials for the issuer, the credentials from the environment of the
+// webhook are used when available. Otherwise, the credentials are read from the secret. Credential plugins,
+// credentials files and Vault belong to the webhook like its environment, so they are only used for issuers allowed
+// to use ambient credentials.
+func (s *ionosCloudDnsProviderResolver) resolveCredentials(ctx context.Context, ch *v1alpha1.ChallengeRequest,
+	config credentialsConfig,
+) (credentials, error) {
+	sources := 0
+	for _, configured := range []bool{
+		config.SecretRef != "", config.Exec != nil, config.hasCredentialsFiles(), config.Vault != nil,
+	} {
+		if configured {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return credentials{}, fmt.Errorf("only one of secretRef, exec, vault and tokenFile/usernameFile/passwordFile can be configured")
+	}
+	if config.Exec != nil {
+		if !ch.AllowAmbientCredentials {
+			return credentials{}, errAmbientSourceNotAllowed("exec")
+		}
+		return s.credentialsFromExec(*config.Exec)
+	}
+	if config.hasCredentialsFiles() {
+		if !ch.AllowAmbientCredentials {
+			return credentials{}, errAmbientSourceNotAllowed("tokenFile/usernameFile/passwordFile")
+		}
+		return s.credentialsFromFiles(config)
+	}
+	if config.Vault != nil {
+		if !ch.AllowAmbientCredentials {
+			return credentials{}, errAmbientSourceNotAllowed("vault")
+		}
+		return s.credentialsFromVault(ctx, config)
+	}
+	if config.SecretRef == "" {
+		creds, found, err := s.ambientCredentials()
+		if err != nil {
+			return credentials{}, err
+		}
+		if found && ch.AllowAmbientCredentials {
+			s.logger.Debug("using ambient credentials")
+			return creds, nil
+		}
+		if found {
+			s.logger.Debug("ambient credentials are not allowed for this issuer, using secret")
+			creds, err := s.credentialsFromSecret(ctx, ch, config)
+			if err != nil {
+				return credentials{}, fmt.Errorf("%w (ambient credentials are not allowed for this issuer)", err)
+			}
+			return creds, nil
+		}
+	}
+	return s.credentialsFromSecret(ctx, ch, config)
+}
+
+// errAmbientSourceNotAllowed is returned if an issuer configures a source of the webhook's own credentials, while
+// cert-manager does not allow it to use ambient credentials.
+func errAmbientSourceNotAllowed(source string) error {
+	return fmt.Errorf("%s can only be used by issuers allowed to use ambient credentials", source)
+}
+
+func (s *ionosCloudDnsProviderResolver) credentialsFromSecret(ctx context.Context, ch *v1alpha1.ChallengeRequest,
+	config credentialsConfig,
+) (credentials, error) {
+	if config.SecretRef == "" {
+		config.SecretRef = defaultSecretName
+	}
+
+	config.applyDefaultKeys()
+
+	secret, secretRef, err := s.getCredentialsSecret(ctx, ch, config)
+	if err != nil {
+		return credentials{}, err
+	}
+
+	return credentials{
+		source:   secretRef,
+		version:  secret.ResourceVersion,
+		token:    string(secret.Data[config.AuthTokenSecretKey]),
+		username: string(secret.Data[config.UsernameSecretKey]),
+		password: string(secret.Data[config.PasswordSecretKey]),
+	}, nil
+}
+
+// ambientCredentials reads the credentials from the environment of the webhook, either from the IONOS_TOKEN or
+// IONOS_USERNAME and IONOS_PASSWORD environment variables, or from the files referenced by IONOS_TOKEN_FILE or
+// IONOS_USERNAME_FILE and IONOS_PASSWORD_FILE.
+func (s *ionosCloudDnsProviderResolver) ambientCredentials() (credentials, bool, error) {
+	creds := credentials{source: ambientCredentialsSource}
+	values := []struct {
+		target  *string
+		envVar  string
+		fileVar string
+	}{
+		{&creds.token, ionoscloud_auth.IonosTokenEnvVar, ambientTokenFileEnvVar},
+		{&creds.username, ionoscloud_auth.IonosUsernameEnvVar, ambientUsernameFileEnvVar},
+		{&creds.password, ionoscloud_auth.IonosPasswordEnvVar, ambientPasswordFileEnvVar},
+	}
+	for _, v := range values {
+		*v.target = s.getenv(v.envVar)
+		if *v.target != "" {
+			continue
+		}
+		if path := s.getenv(v.fileVar); path != "" {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return credentials{}, false, fmt.Errorf("failed to read ambient credentials from %s: %w", path, err)
+			}
+			*v.target = strings.TrimSpace(string(content))
+		}
+	}
+	found := creds.token != "" || creds.username != "" || creds.password != ""
+	return creds, found, nil
+}
+
+// tokenFromCredentials returns the token of the credentials, or a token obtained from the credential plugin or
+// generated from the username and password with the Auth API. Obtained tokens are cached per credentials source and
+// version.
+func (s *ionosCloudDnsProviderResolver) tokenFromCredentials(ctx context.Context, creds credentials,
+	authAPIConfig APIConfig,
+) (string, error) {
+	if creds.exec != nil {
+		token, err := s.tokenCache.get(creds.source, creds.version, s.execToken(ctx, *creds.exec))
+		if err != nil {
+			return "", fmt.Errorf("failed to obtain token from credential plugin: %w", err)
+		}
+		return token, nil
+	}
+	if creds.token != "" {
+		if err := s.checkTokenExpiry(creds); err != nil {
+			return "", err
+		}
+		return creds.token, nil
+	}
+	if creds.username == "" || creds.password == "" {
+		return "", fmt.Errorf("empty username or password: a valid username-password pair should be provided when the token is not provided")
+	}
+	version := creds.version + "/" + credentialsFingerprint(creds.username, creds.password)
+	token, err := s.tokenCache.get(creds.source, version,
+		s.tokenCache.generate(ctx, creds.source, s.authAPIFactory(creds.username, creds.password, authAPIConfig)))
+	if err != nil {
+		return "", fmt.Errorf("failed generate token: %w", err)
+	}
+	s.logger.Debug("using token generated from username and password", zap.String("source", creds.source))
+	return token, nil
+}
+
+// checkTokenExpiry fails for an expired static token, and warns when the token expires soon. The remaining lifetime
+// is exposed as a metric. Tokens without a known expiry are not checked.
+func (s *ionosCloudDnsProviderResolver) checkTokenExpiry(creds credentials) error {
+	expiresAt, ok := tokenExpiry(creds.token)
+	if !ok {
+		return nil
+	}
+	remaining := expiresAt.Sub(s.tokenCache.now())
+	metrics.AuthTokenDaysUntilExpiry.WithLabelValues(creds.source).Set(remaining.Hours() / 24)
+	if remaining <= 0 {
+		return fmt.Errorf("auth token from %s expired at %s", creds.source, expiresAt.Format(time.RFC3339))
+	}
+	if remaining < tokenExpiryWarningPeriod {
+		s.logger.Warn("auth token expires soon, renew it to avoid failing challenges",
+			zap.String("source", creds.source), zap.Time("expiresAt", expiresAt))
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+//go:build unit
+
+package resolver
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCredentialsConfigFor(t *testing.T) {
+	const config = `{
+		"secretRef": "top-level",
+		"credentials": [
+			{"domains": ["example.com", "Example.ORG."], "secretRef": "contract-a"},
+			{"domains": ["sub.example.com"], "secretRef": "shadowed"},
+			{"domains": ["app.example.net"], "secretRef": "contract-b"},
+			{"secretRef": "default"}
+		]
+	}`
+	testCases := []struct {
+		name          string
+		givenConfig   string
+		whenZone      string
+		whenDNSName   string
+		thenSecretRef string
+		thenError     string
+	}{
+		{
+			name:          "zone matches domain",
+			givenConfig:   config,
+			whenZone:      "example.com.",
+			whenDNSName:   "example.com",
+			thenSecretRef: "contract-a",
+		},
+		{
+			name:          "first matching entry wins",
+			givenConfig:   config,
+			whenZone:      "sub.example.com.",
+			whenDNSName:   "*.sub.example.com",
+			thenSecretRef: "contract-a",
+		},
+		{
+			name:          "domains are case insensitive",
+			givenConfig:   config,
+			whenZone:      "example.org.",
+			whenDNSName:   "www.example.org",
+			thenSecretRef: "contract-a",
+		},
+		{
+			name:          "dns name matches domain",
+			givenConfig:   config,
+			whenZone:      "example.net.",
+			whenDNSName:   "*.app.example.net",
+			thenSecretRef: "contract-b",
+		},
+		{
+			name:          "suffix must match at a label boundary",
+			givenConfig:   config,
+			whenZone:      "notexample.com.",
+			whenDNSName:   "notexample.com",
+			thenSecretRef: "default",
+		},
+		{
+			name:          "no credentials list",
+			givenConfig:   `{"secretRef": "top-level"}`,
+			whenZone:      "example.com.",
+			whenDNSName:   "example.com",
+			thenSecretRef: "top-level",
+		},
+		{
+			name:        "no match and no default",
+			givenConfig: `{"credentials": [{"domains": ["example.com"], "secretRef": "contract-a"}]}`,
+			whenZone:    "example.net.",
+			whenDNSName: "example.net",
+			thenError:   "no credentials configured for domain 'example.net'",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var config ionosCloudDNS01SolverConfig
+			require.NoError(t, json.Unmarshal([]byte(tc.givenConfig), &config))
+			ch := &v1alpha1.ChallengeRequest{ResolvedZone: tc.whenZone, DNSName: tc.whenDNSName}
+
+			credentialsConfig, err := credentialsConfigFor(ch, config)
+			if tc.thenError != "" {
+				require.EqualError(t, err, tc.thenError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.thenSecretRef, credentialsConfig.SecretRef)
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package resolver
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/ionos-cloud/cert-manager-webhook-ionos-cloud/internal/clouddns"
+)
+
+// dnsAPICache keeps a DNS API client per credentials source, so that the challenges using the same credentials share
+// the client and its connections instead of creating a new client for every Present and CleanUp call. An entry is
+// replaced when the token or the API configuration of its source changes, and evicted when the credentials change.
+type dnsAPICache struct {
+	mu      sync.Mutex
+	entries map[string]cachedDNSAPI
+	factory DNSAPIFactory
+}
+
+type cachedDNSAPI struct {
+	token      string
+	url        string
+	httpClient *http.Client
+	api        clouddns.DNSAPI
+}
+
+func newDNSAPICache(factory DNSAPIFactory) *dnsAPICache {
+	return &dnsAPICache{
+		entries: make(map[string]cachedDNSAPI),
+		factory: factory,
+	}
+}
+
+// get returns the cached client of the credentials source, or creates a new one if there is none for the token and
+// API configuration.
+func (c *dnsAPICache) get(source, token string, config APIConfig) clouddns.DNSAPI {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[source]
+	if ok && entry.token == token && entry.url == config.URL && entry.httpClient == config.HTTPClient {
+		return entry.api
+	}
+	entry = cachedDNSAPI{
+		token:      token,
+		url:        config.URL,
+		httpClient: config.HTTPClient,
+		api:        c.factory(token, config),
+	}
+	c.entries[source] = entry
+	return entry.api
+}
+
+// evict removes the client of the credentials source.
+func (c *dnsAPICache) evict(source string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, source)
+}
@@ -0,0 +1,123 @@
+//go:build unit
+
+package resolver
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/ionos-cloud/cert-manager-webhook-ionos-cloud/internal/clouddns"
+	"github.com/ionos-cloud/cert-manager-webhook-ionos-cloud/internal/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDNSAPICache(t *testing.T) {
+	httpClient := &http.Client{}
+	testCases := []struct {
+		name            string
+		whenSource      string
+		whenToken       string
+		whenConfig      APIConfig
+		whenEvicted     bool
+		thenFactoryRuns int
+	}{
+		{
+			name:            "same token reuses client",
+			whenSource:      "ns/secret",
+			whenToken:       "token",
+			whenConfig:      APIConfig{HTTPClient: httpClient},
+			thenFactoryRuns: 1,
+		},
+		{
+			name:            "changed token creates new client",
+			whenSource:      "ns/secret",
+			whenToken:       "new-token",
+			whenConfig:      APIConfig{HTTPClient: httpClient},
+			thenFactoryRuns: 2,
+		},
+		{
+			name:            "changed url creates new client",
+			whenSource:      "ns/secret",
+			whenToken:       "token",
+			whenConfig:      APIConfig{URL: "https://dns.example.com", HTTPClient: httpClient},
+			thenFactoryRuns: 2,
+		},
+		{
+			name:            "changed http client creates new client",
+			whenSource:      "ns/secret",
+			whenToken:       "token",
+			whenConfig:      APIConfig{HTTPClient: &http.Client{}},
+			thenFactoryRuns: 2,
+		},
+		{
+			name:            "other source creates new client",
+			whenSource:      "ns/other-secret",
+			whenToken:       "token",
+			whenConfig:      APIConfig{HTTPClient: httpClient},
+			thenFactoryRuns: 2,
+		},
+		{
+			name:            "evicted source creates new client",
+			whenSource:      "ns/secret",
+			whenToken:       "token",
+			whenConfig:      APIConfig{HTTPClient: httpClient},
+			whenEvicted:     true,
+			thenFactoryRuns: 2,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			factoryRuns := 0
+			cache := newDNSAPICache(func(_ string, _ APIConfig) clouddns.DNSAPI {
+				factoryRuns++
+				return mocks.NewDNSAPI(t)
+			})
+
+			first := cache.get("ns/secret", "token", APIConfig{HTTPClient: httpClient})
+			if tc.whenEvicted {
+				cache.evict("ns/secret")
+			}
+			second := cache.get(tc.whenSource, tc.whenToken, tc.whenConfig)
+
+			require.Equal(t, tc.thenFactoryRuns, factoryRuns)
+			if tc.thenFactoryRuns == 1 {
+				require.Same(t, first, second)
+			} else {
+				require.NotSame(t, first, second)
+			}
+		})
+	}
+}
+
+func TestHTTPClientCache(t *testing.T) {
+	cache := newHTTPClientCache()
+	defaultClient, err := cache.get("", nil)
+	require.NoError(t, err)
+	proxyClient, err := cache.get("http://proxy.example.com:3128", nil)
+	require.NoError(t, err)
+
+	client, err := cache.get("", nil)
+	require.NoError(t, err)
+	require.Same(t, defaultClient, client)
+	client, err = cache.get("http://proxy.example.com:3128", nil)
+	require.NoError(t, err)
+	require.Same(t, proxyClient, client)
+	require.NotSame(t, defaultClient, proxyClient)
+	require.Equal(t, maxIdleConns, defaultClient.Transport.(keepAliveTransport).next.MaxIdleConnsPerHost)
+}
+
+func TestHTTPClientCacheIsBounded(t *testing.T) {
+	cache := newHTTPClientCache()
+	first, err := cache.get("http://proxy-0.example.com:3128", nil)
+	require.NoError(t, err)
+	for i := 1; i <= maxHTTPClients; i++ {
+		_, err := cache.get(fmt.Sprintf("http://proxy-%d.example.com:3128", i), nil)
+		require.NoError(t, err)
+	}
+
+	require.Len(t, cache.clients, maxHTTPClients)
+	client, err := cache.get("http://proxy-0.example.com:3128", nil)
+	require.NoError(t, err)
+	require.NotSame(t, first, client, "the least recently used client should have been dropped")
+}
@@ -0,0 +1,160 @@
+package resolver
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const (
+	// maxIdleConns is the maximum number of idle connections kept per HTTP client and per host.
+	maxIdleConns = 32
+	// maxHTTPClients is the maximum number of HTTP clients kept by the httpClientCache.
+	maxHTTPClients = 16
+)
+
+// APIConfig configures how an IONOS Cloud API is reached. An empty URL selects the default endpoint of the SDK, and
+// a nil HTTPClient the default client of the SDK. Account identifies the IONOS Cloud account the calls are made for,
+// which shares the rate limit of the API.
+type APIConfig struct {
+	URL        string
+	HTTPClient *http.Client
+	Account    string
+}
+
+// WithAllowedAPIURLs allows the given URLs to be used as DNS API URL, Auth API URL or proxy URL in the solver config.
+func WithAllowedAPIURLs(urls []string) Option {
+	return func(s *ionosCloudDnsProviderResolver) {
+		for _, u := range urls {
+			s.allowedAPIURLs = append(s.allowedAPIURLs, strings.TrimSuffix(strings.TrimSpace(u), "/"))
+		}
+	}
+}
+
+// endpointsConfig configures the endpoints of the IONOS Cloud APIs for an issuer, e.g. regional endpoints, an
+// egress proxy or a mock of the API.
+type endpointsConfig struct {
+	DNSAPIURL  string `json:"dnsApiUrl"`
+	AuthAPIURL string `json:"authApiUrl"`
+	ProxyURL   string `json:"proxyUrl"`
+	// CABundle is a PEM encoded bundle of CA certificates trusted in addition to the system CAs, base64 encoded in
+	// the solver config.
+	CABundle []byte `json:"caBundle"`
+}
+
+// apiConfigs returns the configuration of the DNS API and of the Auth API. The URLs must be allowed.
+func (s *ionosCloudDnsProviderResolver) apiConfigs(config endpointsConfig) (dnsAPI APIConfig, authAPI APIConfig,
+	err error,
+) {
+	for _, u := range []struct {
+		field string
+		value string
+	}{
+		{"dnsApiUrl", config.DNSAPIURL},
+		{"authApiUrl", config.AuthAPIURL},
+		{"proxyUrl", config.ProxyURL},
+	} {
+		if u.value != "" && !slices.Contains(s.allowedAPIURLs, strings.TrimSuffix(u.value, "/")) {
+			return APIConfig{}, APIConfig{}, fmt.Errorf("%s '%s' is not allowed", u.field, u.value)
+		}
+	}
+	httpClient, err := s.httpClients.get(config.ProxyURL, config.CABundle)
+	if err != nil {
+		return APIConfig{}, APIConfig{}, err
+	}
+	return APIConfig{URL: config.DNSAPIURL, HTTPClient: httpClient},
+		APIConfig{URL: config.AuthAPIURL, HTTPClient: httpClient}, nil
+}
+
+// httpClientCache keeps an HTTP client per proxy and CA bundle, so that the connections to the IONOS Cloud APIs are
+// reused across challenges and credentials. At most maxHTTPClients are kept, the least recently used client is
+// dropped first.
+type httpClientCache struct {
+	mu      sync.Mutex
+	clients map[string]*http.Client
+	// keys are the keys of the clients, from the least to the most recently used.
+	keys []string
+}
+
+func newHTTPClientCache() *httpClientCache {
+	return &httpClientCache{clients: make(map[string]*http.Client)}
+}
+
+func (c *httpClientCache) get(proxyURL string, caBundle []byte) (*http.Client, error) {
+	sum := sha256.Sum256(caBundle)
+	key := proxyURL + "/" + hex.EncodeToString(sum[:])
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if client, ok := c.clients[key]; ok {
+		c.keys = append(slices.DeleteFunc(c.keys, func(k string) bool { return k == key }), key)
+		return client, nil
+	}
+	client, err := newHTTPClient(proxyURL, caBundle)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.keys) >= maxHTTPClients {
+		c.clients[c.keys[0]].CloseIdleConnections()
+		delete(c.clients, c.keys[0])
+		c.keys = c.keys[1:]
+	}
+	c.clients[key] = client
+	c.keys = append(c.keys, key)
+	return client, nil
+}
+
+// keepAliveTransport clears the Close flag which the IONOS Cloud SDKs set on every request, so that the connections
+// are kept open and reused.
+type keepAliveTransport struct {
+	next *http.Transport
+}
+
+func (t keepAliveTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Close {
+		req = req.Clone(req.Context())
+		req.Close = false
+	}
+	return t.next.RoundTrip(req)
+}
+
+func (t keepAliveTransport) CloseIdleConnections() {
+	t.next.CloseIdleConnections()
+}
+
+// newHTTPClient returns an HTTP client using the proxy and trusting the CA bundle. The transport keeps more idle
+// connections per host than the default, as all calls go to the same few hosts, and resumes TLS sessions when a new
+// connection is needed. A single request is bounded by apiCallTimeout, also when the caller passes no deadline.
+func newHTTPClient(proxyURL string, caBundle []byte) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = maxIdleConns
+	transport.MaxIdleConnsPerHost = maxIdleConns
+	transport.TLSClientConfig = &tls.Config{
+		ClientSessionCache: tls.NewLRUClientSessionCache(0),
+		MinVersion:         tls.VersionTLS12,
+	}
+	if proxyURL != "" {
+		proxy, err := url.Parse(proxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxyUrl: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	if len(caBundle) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(caBundle) {
+			return nil, fmt.Errorf("no certificates found in caBundle")
+		}
+		transport.TLSClientConfig.RootCAs = pool
+	}
+	return &http.Client{Transport: keepAliveTransport{next: transport}, Timeout: apiCallTimeout}, nil
+}
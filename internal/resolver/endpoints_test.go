@@ -0,0 +1,161 @@
+//go:build unit
+
+package resolver
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/ionos-cloud/cert-manager-webhook-ionos-cloud/internal/clouddns"
+	ionoscloud_auth "github.com/ionos-cloud/sdk-go-auth"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+func TestCustomEndpoints(t *testing.T) {
+	var authorization string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"items":[]}`))
+	}))
+	t.Cleanup(server.Close)
+	caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	testCases := []struct {
+		name          string
+		givenConfig   endpointsConfig
+		givenAllowed  []string
+		thenErrorText string
+	}{
+		{
+			name:         "custom dns api url with ca bundle",
+			givenConfig:  endpointsConfig{DNSAPIURL: server.URL, CABundle: caBundle},
+			givenAllowed: []string{server.URL + "/"},
+		},
+		{
+			name:          "dns api url not allowed",
+			givenConfig:   endpointsConfig{DNSAPIURL: server.URL, CABundle: caBundle},
+			thenErrorText: "dnsApiUrl '" + server.URL + "' is not allowed",
+		},
+		{
+			name:          "proxy url not allowed",
+			givenConfig:   endpointsConfig{ProxyURL: "http://proxy.example.com:3128"},
+			givenAllowed:  []string{server.URL},
+			thenErrorText: "proxyUrl 'http://proxy.example.com:3128' is not allowed",
+		},
+		{
+			name:          "server certificate not trusted without ca bundle",
+			givenConfig:   endpointsConfig{DNSAPIURL: server.URL},
+			givenAllowed:  []string{server.URL},
+			thenErrorText: "certificate",
+		},
+		{
+			name:          "invalid ca bundle",
+			givenConfig:   endpointsConfig{DNSAPIURL: server.URL, CABundle: []byte("invalid")},
+			givenAllowed:  []string{server.URL},
+			thenErrorText: "no certificates found in caBundle",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			authorization = ""
+			rawConfig, err := json.Marshal(tc.givenConfig)
+			require.NoError(t, err)
+			resolver := NewResolver(testNamespace, nil, NewDNSAPIFactory(clouddns.RetryPolicy{}, nil), nil, zap.NewNop(),
+				WithAllowedAPIURLs(tc.givenAllowed)).(*ionosCloudDnsProviderResolver)
+			resolver.getenv = func(key string) string {
+				if key == ionoscloud_auth.IonosTokenEnvVar {
+					return "ambient-token"
+				}
+				return ""
+			}
+
+			err = resolver.CleanUp(&v1alpha1.ChallengeRequest{
+				Key:                     "test-key",
+				ResolvedZone:            "test.com.",
+				ResolvedFQDN:            "_acme-challenge.test.com.",
+				AllowAmbientCredentials: true,
+				Config:                  &apiextensionsv1.JSON{Raw: rawConfig},
+			})
+
+			if tc.thenErrorText != "" {
+				require.ErrorContains(t, err, tc.thenErrorText)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "Bearer ambient-token", authorization)
+		})
+	}
+}
+
+func TestDNSAPIAccount(t *testing.T) {
+	encode := base64.RawURLEncoding.EncodeToString
+	contractToken := encode([]byte(`{"alg":"RS256","typ":"JWT"}`)) + "." +
+		encode([]byte(`{"identity":{"contractNumber":31721234}}`)) + ".signature"
+	testCases := []struct {
+		name        string
+		givenToken  string
+		thenAccount string
+	}{
+		{name: "contract of the token", givenToken: contractToken, thenAccount: "contract/31721234"},
+		{name: "credentials source without contract", givenToken: "opaque-token", thenAccount: "ambient"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var account string
+			resolver := NewResolver(testNamespace, nil, func(_ string, config APIConfig) clouddns.DNSAPI {
+				account = config.Account
+				return nil
+			}, nil, zap.NewNop()).(*ionosCloudDnsProviderResolver)
+			resolver.getenv = func(key string) string {
+				if key == ionoscloud_auth.IonosTokenEnvVar {
+					return tc.givenToken
+				}
+				return ""
+			}
+			challenge := &v1alpha1.ChallengeRequest{AllowAmbientCredentials: true}
+			config, err := loadSolverConfig(challenge)
+			require.NoError(t, err)
+
+			_, _, err = resolver.newDNSAPI(context.Background(), challenge, config)
+
+			require.NoError(t, err)
+			require.Equal(t, tc.thenAccount, account)
+		})
+	}
+}
+
+func TestHTTPClientReusesConnections(t *testing.T) {
+	var connections atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"items":[]}`))
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			connections.Add(1)
+		}
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	httpClient, err := newHTTPClient("", caBundle)
+	require.NoError(t, err)
+	dnsAPI := DefaultDNSAPIFactory("token", APIConfig{URL: server.URL, HTTPClient: httpClient})
+
+	for range 3 {
+		_, err := dnsAPI.GetZones(context.Background(), "example.com")
+		require.NoError(t, err)
+	}
+	require.Equal(t, int32(1), connections.Load(), "the connection should be reused across calls")
+}
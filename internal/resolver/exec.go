@@ -0,0 +1,134 @@
+package resolver
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	ionoscloud_auth "github.com/ionos-cloud/sdk-go-auth"
+	"go.uber.org/zap"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientauthenticationv1 "k8s.io/client-go/pkg/apis/clientauthentication/v1"
+)
+
+const (
+	execInfoEnvVar       = "KUBERNETES_EXEC_INFO"
+	execCredentialKind   = "ExecCredential"
+	execAPIVersionV1     = "client.authentication.k8s.io/v1"
+	execAPIVersionV1beta = "client.authentication.k8s.io/v1beta1"
+	// execTimeout is the maximum time a credential plugin may run.
+	execTimeout = 30 * time.Second
+)
+
+// ambientCredentialsEnvVars are the variables holding the ambient credentials of the webhook, which are not passed to
+// the credential plugins run on behalf of the issuers.
+var ambientCredentialsEnvVars = []string{
+	ionoscloud_auth.IonosTokenEnvVar, ionoscloud_auth.IonosUsernameEnvVar, ionoscloud_auth.IonosPasswordEnvVar,
+	ambientTokenFileEnvVar, ambientUsernameFileEnvVar, ambientPasswordFileEnvVar,
+}
+
+// execConfig configures a credential plugin which is executed to obtain a token. The plugin uses the protocol of
+// the kubectl exec credential plugins: it receives an ExecCredential in the KUBERNETES_EXEC_INFO environment
+// variable and prints an ExecCredential with the token and its optional expiry to stdout. The plugin runs with the
+// environment of the webhook without the ambient credentials.
+type execConfig struct {
+	Command    string   `json:"command"`
+	Args       []string `json:"args"`
+	APIVersion string   `json:"apiVersion"`
+}
+
+// source returns an identity of the plugin configuration, used to cache the tokens it returns.
+func (c execConfig) source() string {
+	raw, _ := json.Marshal(c)
+	sum := sha256.Sum256(raw)
+	return "exec/" + hex.EncodeToString(sum[:8])
+}
+
+// credentialsFromExec validates the plugin configuration, the command and its arguments must be one of the allowed
+// commands.
+func (s *ionosCloudDnsProviderResolver) credentialsFromExec(config execConfig) (credentials, error) {
+	if config.Command == "" {
+		return credentials{}, fmt.Errorf("credential plugin command must be set")
+	}
+	commandLine := append([]string{config.Command}, config.Args...)
+	if !slices.ContainsFunc(s.execCommands, func(allowed []string) bool {
+		return slices.Equal(allowed, commandLine)
+	}) {
+		return credentials{}, fmt.Errorf("credential plugin command '%s' is not allowed",
+			strings.Join(commandLine, " "))
+	}
+	if config.APIVersion == "" {
+		config.APIVersion = execAPIVersionV1
+	}
+	if config.APIVersion != execAPIVersionV1 && config.APIVersion != execAPIVersionV1beta {
+		return credentials{}, fmt.Errorf("unsupported credential plugin apiVersion '%s'", config.APIVersion)
+	}
+	return credentials{source: config.source(), exec: &config}, nil
+}
+
+// execToken returns a function running the credential plugin, which is killed when the given context is cancelled.
+// The expiry of the token is taken from the expirationTimestamp returned by the plugin, or from the token itself.
+func (s *ionosCloudDnsProviderResolver) execToken(ctx context.Context, config execConfig) func() (issuedToken, error) {
+	return func() (issuedToken, error) {
+		execInfo, err := json.Marshal(&clientauthenticationv1.ExecCredential{
+			TypeMeta: v1.TypeMeta{APIVersion: config.APIVersion, Kind: execCredentialKind},
+			Spec:     clientauthenticationv1.ExecCredentialSpec{Interactive: false},
+		})
+		if err != nil {
+			return issuedToken{}, fmt.Errorf("failed to encode exec info: %w", err)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, execTimeout)
+		defer cancel()
+		cmd := exec.CommandContext(ctx, config.Command, config.Args...)
+		cmd.Env = append(pluginEnviron(), execInfoEnvVar+"="+string(execInfo))
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		s.logger.Debug("running credential plugin", zap.String("command", config.Command))
+		if err := cmd.Run(); err != nil {
+			return issuedToken{}, fmt.Errorf("credential plugin %s failed: %w: %s", config.Command, err,
+				strings.TrimSpace(stderr.String()))
+		}
+
+		var execCredential clientauthenticationv1.ExecCredential
+		if err := json.Unmarshal(stdout.Bytes(), &execCredential); err != nil {
+			return issuedToken{}, fmt.Errorf("failed to decode output of credential plugin %s: %w", config.Command, err)
+		}
+		if execCredential.APIVersion != config.APIVersion || execCredential.Kind != execCredentialKind {
+			return issuedToken{}, fmt.Errorf("credential plugin %s returned %s %s, expected %s %s", config.Command,
+				execCredential.APIVersion, execCredential.Kind, config.APIVersion, execCredentialKind)
+		}
+		if execCredential.Status == nil || execCredential.Status.Token == "" {
+			return issuedToken{}, fmt.Errorf("credential plugin %s returned no token", config.Command)
+		}
+
+		issued := issuedToken{token: execCredential.Status.Token}
+		if execCredential.Status.ExpirationTimestamp != nil {
+			issued.expiresAt = execCredential.Status.ExpirationTimestamp.Time
+		} else if expiresAt, ok := tokenExpiry(issued.token); ok {
+			issued.expiresAt = expiresAt
+		}
+		return issued, nil
+	}
+}
+
+// pluginEnviron returns the environment of the webhook without the ambient credentials.
+func pluginEnviron() []string {
+	var environ []string
+	for _, env := range os.Environ() {
+		name, _, _ := strings.Cut(env, "=")
+		if !slices.Contains(ambientCredentialsEnvVars, name) {
+			environ = append(environ, env)
+		}
+	}
+	return environ
+}
@@ -0,0 +1,160 @@
+//go:build unit
+
+package resolver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const testExecPlugin = `#!/bin/sh
+echo "$KUBERNETES_EXEC_INFO" >> "$1"
+if [ -n "$3" ]; then
+  echo "$3" >&2
+  exit 1
+fi
+printf '%s' "$2"
+`
+
+func TestExecCredentialPlugin(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	testCases := []struct {
+		name                  string
+		givenOutput           string
+		givenFailure          string
+		givenCommand          string
+		givenArgs             []string
+		whenAmbientNotAllowed bool
+		thenToken             string
+		thenCalls             int
+		thenErrorText         string
+	}{
+		{
+			name: "token with expiration timestamp is cached",
+			givenOutput: `{"apiVersion":"client.authentication.k8s.io/v1","kind":"ExecCredential","status":{"token":"exec-token",` +
+				`"expirationTimestamp":"` + expiresAt.Format(time.RFC3339) + `"}}`,
+			thenToken: "exec-token",
+			thenCalls: 1,
+		},
+		{
+			name: "jwt expiry is used without expiration timestamp",
+			givenOutput: `{"apiVersion":"client.authentication.k8s.io/v1","kind":"ExecCredential","status":{"token":"` +
+				testJWT(expiresAt) + `"}}`,
+			thenToken: testJWT(expiresAt),
+			thenCalls: 1,
+		},
+		{
+			name:        "token without expiry is not cached",
+			givenOutput: `{"apiVersion":"client.authentication.k8s.io/v1","kind":"ExecCredential","status":{"token":"exec-token"}}`,
+			thenToken:   "exec-token",
+			thenCalls:   2,
+		},
+		{
+			name:          "plugin failure includes stderr",
+			givenFailure:  "no credentials available",
+			thenCalls:     2,
+			thenErrorText: "no credentials available",
+		},
+		{
+			name:          "unexpected api version",
+			givenOutput:   `{"apiVersion":"client.authentication.k8s.io/v1beta1","kind":"ExecCredential","status":{"token":"exec-token"}}`,
+			thenCalls:     2,
+			thenErrorText: "expected client.authentication.k8s.io/v1 ExecCredential",
+		},
+		{
+			name:          "missing token",
+			givenOutput:   `{"apiVersion":"client.authentication.k8s.io/v1","kind":"ExecCredential","status":{}}`,
+			thenCalls:     2,
+			thenErrorText: "returned no token",
+		},
+		{
+			name:          "command not allowed",
+			givenCommand:  "/bin/sh",
+			givenArgs:     []string{"-c", "echo"},
+			thenErrorText: "credential plugin command '/bin/sh -c echo' is not allowed",
+		},
+		{
+			name:          "args not allowed",
+			givenArgs:     []string{"--profile", "dns"},
+			thenErrorText: "--profile dns' is not allowed",
+		},
+		{
+			name:                  "ambient credentials not allowed for the issuer",
+			whenAmbientNotAllowed: true,
+			thenErrorText:         "exec can only be used by issuers allowed to use ambient credentials",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			plugin := filepath.Join(dir, "plugin.sh")
+			callsFile := filepath.Join(dir, "calls")
+			require.NoError(t, os.WriteFile(plugin, []byte(testExecPlugin), 0o700))
+			args := []string{callsFile, tc.givenOutput, tc.givenFailure}
+			solverConfig := credentialsConfig{Exec: &execConfig{Command: plugin, Args: args}}
+			if tc.givenCommand != "" {
+				solverConfig.Exec.Command = tc.givenCommand
+			}
+			if tc.givenArgs != nil {
+				solverConfig.Exec.Args = tc.givenArgs
+			}
+			resolver := NewResolver(testNamespace, nil, nil, nil, zap.NewNop(),
+				WithExecPluginCommands([][]string{append([]string{plugin}, args...)})).(*ionosCloudDnsProviderResolver)
+			challenge := &v1alpha1.ChallengeRequest{AllowAmbientCredentials: !tc.whenAmbientNotAllowed}
+
+			for range 2 {
+				creds, err := resolver.resolveCredentials(context.Background(), challenge, solverConfig)
+				var token string
+				if err == nil {
+					token, err = resolver.tokenFromCredentials(context.Background(), creds, APIConfig{})
+				}
+				if tc.thenErrorText != "" {
+					require.ErrorContains(t, err, tc.thenErrorText)
+				} else {
+					require.NoError(t, err)
+					require.Equal(t, tc.thenToken, token)
+				}
+			}
+
+			calls, _ := os.ReadFile(callsFile)
+			lines := strings.Split(strings.TrimSpace(string(calls)), "\n")
+			if tc.thenCalls == 0 {
+				require.Empty(t, strings.TrimSpace(string(calls)))
+				return
+			}
+			require.Len(t, lines, tc.thenCalls)
+			require.JSONEq(t, `{"apiVersion":"client.authentication.k8s.io/v1","kind":"ExecCredential",`+
+				`"spec":{"interactive":false}}`, lines[0])
+		})
+	}
+}
+
+func TestExecCredentialPluginEnvironment(t *testing.T) {
+	t.Setenv("IONOS_TOKEN", "ambient-token")
+	t.Setenv("IONOS_USERNAME", "ambient-username")
+	t.Setenv("IONOS_PASSWORD", "ambient-password")
+	t.Setenv("IONOS_TOKEN_FILE", "/ambient/token")
+	t.Setenv("PLUGIN_SETTING", "kept")
+	plugin := filepath.Join(t.TempDir(), "plugin.sh")
+	require.NoError(t, os.WriteFile(plugin, []byte(`#!/bin/sh
+printf '{"apiVersion":"client.authentication.k8s.io/v1","kind":"ExecCredential","status":{"token":"%s|%s"}}' \
+  "$IONOS_TOKEN$IONOS_USERNAME$IONOS_PASSWORD$IONOS_TOKEN_FILE" "$PLUGIN_SETTING"
+`), 0o700))
+	resolver := NewResolver(testNamespace, nil, nil, nil, zap.NewNop(),
+		WithExecPluginCommands([][]string{{plugin}})).(*ionosCloudDnsProviderResolver)
+
+	creds, err := resolver.resolveCredentials(context.Background(), &v1alpha1.ChallengeRequest{AllowAmbientCredentials: true},
+		credentialsConfig{Exec: &execConfig{Command: plugin}})
+	require.NoError(t, err)
+	token, err := resolver.tokenFromCredentials(context.Background(), creds, APIConfig{})
+	require.NoError(t, err)
+	require.Equal(t, "|kept", token, "ambient credentials should not be passed to the plugin")
+}
@@ -0,0 +1,67 @@
+package resolver
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// credentialsFromFiles reads the credentials from the files configured in the solver config, e.g. mounted by a CSI
+// secrets driver. The files are read for every challenge, so that changes on disk are picked up without restarting
+// the webhook. Only files inside the allowed directories can be read.
+func (s *ionosCloudDnsProviderResolver) credentialsFromFiles(config credentialsConfig) (credentials, error) {
+	creds := credentials{source: "file/" + config.TokenFile + ":" + config.UsernameFile + ":" + config.PasswordFile}
+	values := []struct {
+		target *string
+		path   string
+	}{
+		{&creds.token, config.TokenFile},
+		{&creds.username, config.UsernameFile},
+		{&creds.password, config.PasswordFile},
+	}
+	for _, v := range values {
+		if v.path == "" {
+			continue
+		}
+		content, err := s.readCredentialsFile(v.path)
+		if err != nil {
+			return credentials{}, err
+		}
+		*v.target = content
+	}
+	return creds, nil
+}
+
+func (s *ionosCloudDnsProviderResolver) readCredentialsFile(path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		return "", fmt.Errorf("credentials file '%s' must be an absolute path", path)
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read credentials file %s: %w", path, err)
+	}
+	if !s.credentialsFileAllowed(resolved) {
+		return "", fmt.Errorf("credentials file '%s' is not in an allowed directory", path)
+	}
+	content, err := os.ReadFile(resolved)
+	if err != nil {
+		return "", fmt.Errorf("failed to read credentials file %s: %w", path, err)
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+// credentialsFileAllowed checks that the path, with all symlinks resolved, is inside one of the allowed directories.
+func (s *ionosCloudDnsProviderResolver) credentialsFileAllowed(path string) bool {
+	for _, dir := range s.credentialsFileDirs {
+		resolvedDir, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(resolvedDir, path)
+		if err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+//go:build unit
+
+package resolver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/ionos-cloud/cert-manager-webhook-ionos-cloud/internal/cloudauth"
+	"github.com/ionos-cloud/cert-manager-webhook-ionos-cloud/internal/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestCredentialsFiles(t *testing.T) {
+	allowedDir, otherDir := t.TempDir(), t.TempDir()
+	writeFile := func(dir, name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content+"\n"), 0o600))
+		return path
+	}
+	tokenFile := writeFile(allowedDir, "token", "file-token")
+	otherFile := writeFile(otherDir, "token", "other-token")
+	symlink := filepath.Join(allowedDir, "link")
+	require.NoError(t, os.Symlink(otherFile, symlink))
+
+	testCases := []struct {
+		name                  string
+		givenConfig           credentialsConfig
+		whenAmbientNotAllowed bool
+		thenToken             string
+		thenErrorText         string
+	}{
+		{
+			name:        "token file",
+			givenConfig: credentialsConfig{TokenFile: tokenFile},
+			thenToken:   "file-token",
+		},
+		{
+			name:          "file outside of the allowed directories",
+			givenConfig:   credentialsConfig{TokenFile: otherFile},
+			thenErrorText: "is not in an allowed directory",
+		},
+		{
+			name:          "symlink pointing outside of the allowed directories",
+			givenConfig:   credentialsConfig{TokenFile: symlink},
+			thenErrorText: "is not in an allowed directory",
+		},
+		{
+			name:          "relative path",
+			givenConfig:   credentialsConfig{TokenFile: "token"},
+			thenErrorText: "must be an absolute path",
+		},
+		{
+			name:          "missing file",
+			givenConfig:   credentialsConfig{TokenFile: filepath.Join(allowedDir, "missing")},
+			thenErrorText: "failed to read credentials file",
+		},
+		{
+			name:                  "ambient credentials not allowed for the issuer",
+			givenConfig:           credentialsConfig{TokenFile: tokenFile},
+			whenAmbientNotAllowed: true,
+			thenErrorText:         "tokenFile/usernameFile/passwordFile can only be used by issuers allowed to use ambient credentials",
+		},
+		{
+			name:          "combined with secretRef",
+			givenConfig:   credentialsConfig{TokenFile: tokenFile, SecretRef: "secret"},
+			thenErrorText: "only one of secretRef, exec, vault and tokenFile/usernameFile/passwordFile can be configured",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resolver := NewResolver(testNamespace, nil, nil, nil, zap.NewNop(),
+				WithCredentialsFileDirs([]string{allowedDir})).(*ionosCloudDnsProviderResolver)
+			challenge := &v1alpha1.ChallengeRequest{AllowAmbientCredentials: !tc.whenAmbientNotAllowed}
+			creds, err := resolver.resolveCredentials(context.Background(), challenge, tc.givenConfig)
+			if tc.thenErrorText != "" {
+				require.ErrorContains(t, err, tc.thenErrorText)
+				return
+			}
+			require.NoError(t, err)
+			token, err := resolver.tokenFromCredentials(context.Background(), creds, APIConfig{})
+			require.NoError(t, err)
+			require.Equal(t, tc.thenToken, token)
+		})
+	}
+}
+
+func TestCredentialsFilesAreReloaded(t *testing.T) {
+	dir := t.TempDir()
+	usernameFile, passwordFile := filepath.Join(dir, "username"), filepath.Join(dir, "password")
+	require.NoError(t, os.WriteFile(usernameFile, []byte("user"), 0o600))
+	require.NoError(t, os.WriteFile(passwordFile, []byte("first"), 0o600))
+	authAPIMock := mocks.NewAuthAPI(t)
+	authAPIMock.EXPECT().GenerateToken(mock.Anything, int32(3600)).Return("first-token", nil).Once()
+	authAPIMock.EXPECT().GenerateToken(mock.Anything, int32(3600)).Return("second-token", nil).Once()
+	var passwords []string
+	resolver := NewResolver(testNamespace, nil, nil, func(_, password string, _ APIConfig) cloudauth.AuthAPI {
+		passwords = append(passwords, password)
+		return authAPIMock
+	}, zap.NewNop(), WithCredentialsFileDirs([]string{dir})).(*ionosCloudDnsProviderResolver)
+	config := credentialsConfig{UsernameFile: usernameFile, PasswordFile: passwordFile}
+	getToken := func() string {
+		creds, err := resolver.resolveCredentials(context.Background(),
+			&v1alpha1.ChallengeRequest{AllowAmbientCredentials: true}, config)
+		require.NoError(t, err)
+		token, err := resolver.tokenFromCredentials(context.Background(), creds, APIConfig{})
+		require.NoError(t, err)
+		return token
+	}
+
+	require.Equal(t, "first-token", getToken())
+	require.NoError(t, os.WriteFile(passwordFile, []byte("second"), 0o600))
+	require.Equal(t, "second-token", getToken())
+	require.Equal(t, []string{"first", "second"}, passwords)
+}
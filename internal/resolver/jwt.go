@@ -0,0 +1,61 @@
+package resolver
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// jwtHeader contains the subset of the IONOS Cloud token header used by the resolver.
+type jwtHeader struct {
+	KeyID string `json:"kid"`
+}
+
+// jwtClaims contains the subset of the IONOS Cloud token claims used by the resolver.
+type jwtClaims struct {
+	ExpiresAt int64 `json:"exp"`
+	Identity  struct {
+		ContractNumber json.Number `json:"contractNumber"`
+	} `json:"identity"`
+}
+
+// tokenExpiry returns the expiry time encoded in the exp claim of the given JWT.
+// The signature is not verified, the token is only inspected to decide for how long it can be used.
+func tokenExpiry(token string) (time.Time, bool) {
+	var claims jwtClaims
+	if !decodeJWTSegment(token, 1, &claims) || claims.ExpiresAt == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(claims.ExpiresAt, 0), true
+}
+
+// contractNumber returns the number of the IONOS Cloud contract the token belongs to.
+func contractNumber(token string) (string, bool) {
+	var claims jwtClaims
+	if !decodeJWTSegment(token, 1, &claims) || claims.Identity.ContractNumber == "" {
+		return "", false
+	}
+	return claims.Identity.ContractNumber.String(), true
+}
+
+// tokenID returns the id of an IONOS Cloud token, which is carried in the kid header of the JWT.
+func tokenID(token string) (string, bool) {
+	var header jwtHeader
+	if !decodeJWTSegment(token, 0, &header) || header.KeyID == "" {
+		return "", false
+	}
+	return header.KeyID, true
+}
+
+func decodeJWTSegment(token string, index int, v any) bool {
+	segments := strings.Split(token, ".")
+	if len(segments) != 3 {
+		return false
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(segments[index], "="))
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(raw, v) == nil
+}
@@ -0,0 +1,149 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ionos-cloud/cert-manager-webhook-ionos-cloud/internal/clouddns"
+	ionoscloud "github.com/ionos-cloud/sdk-go-dns"
+	"go.uber.org/zap"
+)
+
+const (
+	// recordPollInterval is the time between two reads of a record or zone while waiting for its provisioning state.
+	recordPollInterval = 2 * time.Second
+	// zoneProvisioningTimeout bounds the wait for a zone created by the webhook to become AVAILABLE, leaving part of
+	// the requestTimeout for the record.
+	zoneProvisioningTimeout = 30 * time.Second
+)
+
+// WithRecordProvisioningWait makes Present wait until a created record is AVAILABLE, and CleanUp until a deleted
+// record is gone, for at most the given timeouts. A timeout of zero disables the wait. The waits end with the
+// requestTimeout of the call at the latest.
+func WithRecordProvisioningWait(provisioningTimeout, deletionTimeout time.Duration) Option {
+	return func(s *ionosCloudDnsProviderResolver) {
+		s.recordProvisioningTimeout = provisioningTimeout
+		s.recordDeletionTimeout = deletionTimeout
+	}
+}
+
+// waitForRecordAvailable polls the record until its state is AVAILABLE, and fails if its provisioning failed.
+func (s *ionosCloudDnsProviderResolver) waitForRecordAvailable(ctx context.Context, client clouddns.DNSAPI, zoneId string,
+	recordId string,
+) error {
+	if s.recordProvisioningTimeout <= 0 {
+		return nil
+	}
+	return s.pollRecord(ctx, client, zoneId, recordId, s.recordProvisioningTimeout,
+		func(record ionoscloud.RecordRead, err error) (bool, error) {
+			if err != nil {
+				return false, err
+			}
+			return recordState(record) == ionoscloud.PROVISIONINGSTATE_AVAILABLE, nil
+		})
+}
+
+// waitForRecordDeleted polls the record until it is not found anymore.
+func (s *ionosCloudDnsProviderResolver) waitForRecordDeleted(ctx context.Context, client clouddns.DNSAPI, zoneId string,
+	recordId string,
+) error {
+	if s.recordDeletionTimeout <= 0 {
+		return nil
+	}
+	return s.pollRecord(ctx, client, zoneId, recordId, s.recordDeletionTimeout,
+		func(_ ionoscloud.RecordRead, err error) (bool, error) {
+			if clouddns.IsNotFound(err) {
+				return true, nil
+			}
+			return false, err
+		})
+}
+
+// waitForZoneAvailable polls the zone until its state is AVAILABLE, and fails if its provisioning failed.
+func (s *ionosCloudDnsProviderResolver) waitForZoneAvailable(ctx context.Context, client clouddns.DNSAPI,
+	zoneId string,
+) (ionoscloud.ZoneRead, error) {
+	var zone ionoscloud.ZoneRead
+	err := s.poll(ctx, "zone", zoneId, zoneProvisioningTimeout, func(callCtx context.Context) (
+		ionoscloud.ProvisioningState, bool, error,
+	) {
+		var err error
+		zone, err = client.GetZone(callCtx, zoneId)
+		if err != nil {
+			return "", false, err
+		}
+		state := zoneState(zone)
+		return state, state == ionoscloud.PROVISIONINGSTATE_AVAILABLE, nil
+	})
+	return zone, err
+}
+
+// pollRecord reads the record until done reports true or an error, the record is FAILED, or the timeout expires.
+func (s *ionosCloudDnsProviderResolver) pollRecord(ctx context.Context, client clouddns.DNSAPI, zoneId string,
+	recordId string, timeout time.Duration, done func(ionoscloud.RecordRead, error) (bool, error),
+) error {
+	return s.poll(ctx, "record", recordId, timeout, func(callCtx context.Context) (
+		ionoscloud.ProvisioningState, bool, error,
+	) {
+		record, err := client.GetRecord(callCtx, zoneId, recordId)
+		ok, err := done(record, err)
+		return recordState(record), ok, err
+	})
+}
+
+// poll calls read until it reports done or an error, the resource is FAILED, or the timeout expires.
+func (s *ionosCloudDnsProviderResolver) poll(ctx context.Context, kind string, id string, timeout time.Duration,
+	read func(ctx context.Context) (ionoscloud.ProvisioningState, bool, error),
+) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var state ionoscloud.ProvisioningState
+	for {
+		callCtx, callCancel := context.WithTimeout(ctx, apiCallTimeout)
+		readState, ok, err := read(callCtx)
+		callCancel()
+		if ctx.Err() != nil {
+			return waitError(ctx, kind, id, state, timeout)
+		}
+		if err != nil || ok {
+			return err
+		}
+		state = readState
+		if state == ionoscloud.PROVISIONINGSTATE_FAILED {
+			return fmt.Errorf("%s %s is %s", kind, id, state)
+		}
+		s.logger.Debug("waiting for "+kind, zap.String("id", id), zap.String("state", string(state)))
+		select {
+		case <-ctx.Done():
+			return waitError(ctx, kind, id, state, timeout)
+		case <-time.After(s.recordPollInterval):
+		}
+	}
+}
+
+// waitError returns the error for a wait which ended with the context, either by the timeout or by the
+// cancellation of the challenge.
+func waitError(ctx context.Context, kind string, id string, state ionoscloud.ProvisioningState,
+	timeout time.Duration,
+) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%s %s is still %s after %s", kind, id, state, timeout)
+	}
+	return ctx.Err()
+}
+
+func recordState(record ionoscloud.RecordRead) ionoscloud.ProvisioningState {
+	if record.Metadata == nil || record.Metadata.State == nil {
+		return ""
+	}
+	return *record.Metadata.State
+}
+
+func zoneState(zone ionoscloud.ZoneRead) ionoscloud.ProvisioningState {
+	if zone.Metadata == nil || zone.Metadata.State == nil {
+		return ""
+	}
+	return *zone.Metadata.State
+}
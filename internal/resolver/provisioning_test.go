@@ -0,0 +1,130 @@
+//go:build unit
+
+package resolver
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/ionos-cloud/cert-manager-webhook-ionos-cloud/internal/clouddns"
+	"github.com/ionos-cloud/cert-manager-webhook-ionos-cloud/internal/mocks"
+	ionoscloud "github.com/ionos-cloud/sdk-go-dns"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRecordProvisioningWait(t *testing.T) {
+	testCases := []struct {
+		name          string
+		whenDeletion  bool
+		givenStates   []ionoscloud.ProvisioningState
+		givenNotFound bool
+		thenCalls     int
+		thenErrorText string
+	}{
+		{
+			name:        "record becomes available",
+			givenStates: []ionoscloud.ProvisioningState{ionoscloud.PROVISIONINGSTATE_PROVISIONING, ionoscloud.PROVISIONINGSTATE_AVAILABLE},
+			thenCalls:   2,
+		},
+		{
+			name:          "record provisioning fails",
+			givenStates:   []ionoscloud.ProvisioningState{ionoscloud.PROVISIONINGSTATE_PROVISIONING, ionoscloud.PROVISIONINGSTATE_FAILED},
+			thenCalls:     2,
+			thenErrorText: "record record-id is FAILED",
+		},
+		{
+			name:          "record stays provisioning",
+			givenStates:   []ionoscloud.ProvisioningState{ionoscloud.PROVISIONINGSTATE_PROVISIONING},
+			thenErrorText: "record record-id is still PROVISIONING after 50ms",
+		},
+		{
+			name:          "deleted record is gone",
+			whenDeletion:  true,
+			givenStates:   []ionoscloud.ProvisioningState{ionoscloud.PROVISIONINGSTATE_DESTROYING},
+			givenNotFound: true,
+			thenCalls:     2,
+		},
+		{
+			name:          "deleted record stays destroying",
+			whenDeletion:  true,
+			givenStates:   []ionoscloud.ProvisioningState{ionoscloud.PROVISIONINGSTATE_DESTROYING},
+			thenErrorText: "record record-id is still DESTROYING after 50ms",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dnsAPIMock := mocks.NewDNSAPI(t)
+			for i, state := range tc.givenStates {
+				call := dnsAPIMock.EXPECT().GetRecord(mock.Anything, "zone-id", "record-id").Return(ionoscloud.RecordRead{
+					Id:       toPTR("record-id"),
+					Metadata: &ionoscloud.MetadataWithStateFqdnZoneId{State: toPTR(state)},
+				}, nil)
+				if i < len(tc.givenStates)-1 || tc.givenNotFound {
+					call.Once()
+				}
+			}
+			if tc.givenNotFound {
+				dnsAPIMock.EXPECT().GetRecord(mock.Anything, "zone-id", "record-id").
+					Return(ionoscloud.RecordRead{}, &clouddns.APIError{StatusCode: http.StatusNotFound}).Once()
+			}
+			resolver := NewResolver(testNamespace, nil, nil, nil, zap.NewNop(),
+				WithRecordProvisioningWait(50*time.Millisecond, 50*time.Millisecond)).(*ionosCloudDnsProviderResolver)
+			resolver.recordPollInterval = time.Millisecond
+
+			var err error
+			if tc.whenDeletion {
+				err = resolver.waitForRecordDeleted(context.Background(), dnsAPIMock, "zone-id", "record-id")
+			} else {
+				err = resolver.waitForRecordAvailable(context.Background(), dnsAPIMock, "zone-id", "record-id")
+			}
+
+			if tc.thenErrorText != "" {
+				require.EqualError(t, err, tc.thenErrorText)
+			} else {
+				require.NoError(t, err)
+			}
+			if tc.thenCalls > 0 {
+				dnsAPIMock.AssertNumberOfCalls(t, "GetRecord", tc.thenCalls)
+			}
+		})
+	}
+}
+
+func TestRecordProvisioningWaitDisabled(t *testing.T) {
+	resolver := NewResolver(testNamespace, nil, nil, nil, zap.NewNop()).(*ionosCloudDnsProviderResolver)
+	dnsAPIMock := mocks.NewDNSAPI(t)
+
+	require.NoError(t, resolver.waitForRecordAvailable(context.Background(), dnsAPIMock, "zone-id", "record-id"))
+	require.NoError(t, resolver.waitForRecordDeleted(context.Background(), dnsAPIMock, "zone-id", "record-id"))
+}
+
+func TestFailedRecordIsReplaced(t *testing.T) {
+	dnsAPIMock := mocks.NewDNSAPI(t)
+	challenge := &v1alpha1.ChallengeRequest{Key: "test-key", ResolvedFQDN: "_acme-challenge.test.com."}
+	dnsAPIMock.EXPECT().ListRecords(mock.Anything, "zone-id", txtRecordFilter("_acme-challenge")).
+		Return(ionoscloud.RecordReadList{Items: &[]ionoscloud.RecordRead{{
+			Id:         toPTR("failed-record-id"),
+			Properties: &ionoscloud.Record{Content: toPTR("test-key")},
+			Metadata:   &ionoscloud.MetadataWithStateFqdnZoneId{State: toPTR(ionoscloud.PROVISIONINGSTATE_FAILED)},
+		}}}, nil).Once()
+	dnsAPIMock.EXPECT().DeleteRecord(mock.Anything, "zone-id", "failed-record-id").Return(nil).Once()
+	dnsAPIMock.EXPECT().GetRecord(mock.Anything, "zone-id", "failed-record-id").
+		Return(ionoscloud.RecordRead{}, &clouddns.APIError{StatusCode: http.StatusNotFound}).Once()
+	dnsAPIMock.EXPECT().CreateRecord(mock.Anything, "zone-id", clouddns.NewTXTRecord("_acme-challenge", "test-key", 60)).
+		Return(ionoscloud.RecordRead{Id: toPTR("record-id")}, nil).Once()
+	dnsAPIMock.EXPECT().GetRecord(mock.Anything, "zone-id", "record-id").Return(ionoscloud.RecordRead{
+		Id:       toPTR("record-id"),
+		Metadata: &ionoscloud.MetadataWithStateFqdnZoneId{State: toPTR(ionoscloud.PROVISIONINGSTATE_AVAILABLE)},
+	}, nil).Once()
+	resolver := NewResolver(testNamespace, nil, nil, nil, zap.NewNop(),
+		WithRecordProvisioningWait(time.Second, time.Second)).(*ionosCloudDnsProviderResolver)
+	resolver.recordPollInterval = time.Millisecond
+
+	require.NoError(t, resolver.findOrCreateRecord(context.Background(), challenge,
+		dnsZone{id: "zone-id", name: "test.com"}, 60, dnsAPIMock))
+}
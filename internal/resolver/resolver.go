@@ -4,16 +4,21 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net"
+	"os"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/ionos-cloud/cert-manager-webhook-ionos-cloud/internal/cloudauth"
 	"github.com/ionos-cloud/cert-manager-webhook-ionos-cloud/internal/clouddns"
+	"github.com/ionos-cloud/cert-manager-webhook-ionos-cloud/internal/vault"
 	ionoscloud_auth "github.com/ionos-cloud/sdk-go-auth"
 	ionoscloud "github.com/ionos-cloud/sdk-go-dns"
 
 	"github.com/cert-manager/cert-manager/pkg/acme/webhook"
 	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
 	"go.uber.org/zap"
-	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
@@ -21,48 +26,205 @@ import (
 )
 
 const (
+	// requestTimeout is the maximum duration of a Present or CleanUp call, including the waits for provisioning. It
+	// stays below the 60 second request timeout of the webhook server, so that the call can still return its error.
+	requestTimeout = 50 * time.Second
+	// apiCallTimeout is the maximum duration of a single IONOS Cloud API call.
+	apiCallTimeout = 30 * time.Second
+
 	defaultSecretName         = "cert-manager-webhook-ionos-cloud"
 	defaultAuthTokenSecretKey = "auth-token"
 	defaultUsernameSecretKey  = "username"
 	defaultPasswordSecretKey  = "password"
+
+	// defaultRecordTTL is the TTL of the challenge records, low to let validation retries see a changed record.
+	defaultRecordTTL = 60
+	// minRecordTTL and maxRecordTTL are the limits of the IONOS Cloud DNS API for the TTL of records.
+	minRecordTTL = 60
+	maxRecordTTL = 86400
 )
 
 type K8Client interface {
 	CoreV1() corev1.CoreV1Interface
 }
 
-type DNSAPIFactory func(token string) clouddns.DNSAPI
+type DNSAPIFactory func(token string, config APIConfig) clouddns.DNSAPI
 
 type K8ClientFactory func(cfg *rest.Config) (K8Client, error)
 
-type GenerateTokenFunc func(cfg *ionoscloud_auth.Configuration) (string, error)
+type AuthAPIFactory func(username, password string, config APIConfig) cloudauth.AuthAPI
+
+// Option configures optional behavior of the resolver.
+type Option func(*ionosCloudDnsProviderResolver)
+
+// WithSecretInformer serves the credentials secrets from a shared informer instead of fetching them for every
+// challenge. The informer watches the namespace of the webhook, or all namespaces if allNamespaces is set, and is
+// limited to the secrets matching the label selector, which is required when watching all namespaces.
+func WithSecretInformer(allNamespaces bool, labelSelector string) Option {
+	return func(s *ionosCloudDnsProviderResolver) {
+		s.secretInformerConfig = &secretInformerConfig{
+			allNamespaces: allNamespaces,
+			labelSelector: labelSelector,
+		}
+	}
+}
+
+// WithClusterResourceNamespace sets the cluster resource namespace of cert-manager, the namespace of the challenges
+// of ClusterIssuers. It defaults to the namespace of the webhook.
+func WithClusterResourceNamespace(namespace string) Option {
+	return func(s *ionosCloudDnsProviderResolver) {
+		s.clusterResourceNamespace = namespace
+	}
+}
+
+// WithSecretAccess sets the namespaces other than its own in which the webhook may read secrets. The secret of an
+// Issuer is only looked up in the namespace of the Issuer if the webhook may read secrets there.
+func WithSecretAccess(clusterWide bool, namespaces []string) Option {
+	return func(s *ionosCloudDnsProviderResolver) {
+		s.secretAccessClusterWide = clusterWide
+		s.secretAccessNamespaces = namespaces
+	}
+}
+
+type secretInformerConfig struct {
+	allNamespaces bool
+	labelSelector string
+}
+
+// WithTokenSweep enables deleting the expired tokens generated by the webhook, e.g. the replaced tokens, from the
+// IONOS Cloud account of the credentials in use.
+func WithTokenSweep(enabled bool) Option {
+	return func(s *ionosCloudDnsProviderResolver) {
+		s.tokenCache.sweep = enabled
+	}
+}
+
+// WithExecPluginCommands allows the given commands, each the command followed by its arguments, to be used as
+// credential plugins in the solver config. The command and arguments of the solver config must match one of them.
+func WithExecPluginCommands(commands [][]string) Option {
+	return func(s *ionosCloudDnsProviderResolver) {
+		s.execCommands = commands
+	}
+}
+
+// WithCredentialsFileDirs allows the solver config to read credentials from files inside the given directories, e.g.
+// volumes mounted by a CSI secrets driver.
+func WithCredentialsFileDirs(dirs []string) Option {
+	return func(s *ionosCloudDnsProviderResolver) {
+		s.credentialsFileDirs = dirs
+	}
+}
 
 type ionosCloudDNS01SolverConfig struct {
-	SecretRef          string `json:"secretRef"`
-	AuthTokenSecretKey string `json:"authTokenSecretKey"`
-	UsernameSecretKey  string `json:"usernameSecretKey"`
-	PasswordSecretKey  string `json:"passwordSecretKey"`
+	credentialsConfig
+	endpointsConfig
+	zoneConfig
+	// Credentials routes the challenges of different domains to different credentials. If set, the credentials
+	// configured at the top level are ignored.
+	Credentials []domainCredentialsConfig `json:"credentials"`
+	// TTL is the TTL of the challenge records in seconds.
+	TTL *int32 `json:"ttl"`
 }
 
-func NewResolver(namespace string, k8ClientFactory K8ClientFactory, dnsAPIFactory DNSAPIFactory, generateToken GenerateTokenFunc,
-	logger *zap.Logger,
+// domainCredentialsConfig are the credentials used for the challenges of the given domains and their subdomains.
+// Without domains, the credentials are used for the challenges not matching any other entry.
+type domainCredentialsConfig struct {
+	Domains []string `json:"domains"`
+	credentialsConfig
+}
+
+// credentialsConfig configures where the credentials are read from.
+type credentialsConfig struct {
+	SecretRef          string             `json:"secretRef"`
+	SecretNamespace    string             `json:"secretNamespace"`
+	AuthTokenSecretKey string             `json:"authTokenSecretKey"`
+	UsernameSecretKey  string             `json:"usernameSecretKey"`
+	PasswordSecretKey  string             `json:"passwordSecretKey"`
+	Exec               *execConfig        `json:"exec"`
+	TokenFile          string             `json:"tokenFile"`
+	UsernameFile       string             `json:"usernameFile"`
+	PasswordFile       string             `json:"passwordFile"`
+	Vault              *vaultSecretConfig `json:"vault"`
+}
+
+// applyDefaultKeys sets the default keys of the credentials in a secret.
+func (c *credentialsConfig) applyDefaultKeys() {
+	if c.AuthTokenSecretKey == "" {
+		c.AuthTokenSecretKey = defaultAuthTokenSecretKey
+	}
+
+	if c.UsernameSecretKey == "" {
+		c.UsernameSecretKey = defaultUsernameSecretKey
+	}
+
+	if c.PasswordSecretKey == "" {
+		c.PasswordSecretKey = defaultPasswordSecretKey
+	}
+}
+
+func (c credentialsConfig) hasCredentialsFiles() bool {
+	return c.TokenFile != "" || c.UsernameFile != "" || c.PasswordFile != ""
+}
+
+func NewResolver(namespace string, k8ClientFactory K8ClientFactory, dnsAPIFactory DNSAPIFactory, authAPIFactory AuthAPIFactory,
+	logger *zap.Logger, opts ...Option,
 ) webhook.Solver {
-	return &ionosCloudDnsProviderResolver{
-		k8ClientFactory: k8ClientFactory,
-		namespace:       namespace,
-		dnsAPIFactory:   dnsAPIFactory,
-		generateToken:   generateToken,
-		logger:          logger,
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &ionosCloudDnsProviderResolver{
+		ctx:                ctx,
+		cancel:             cancel,
+		k8ClientFactory:    k8ClientFactory,
+		namespace:          namespace,
+		dnsAPIs:            newDNSAPICache(dnsAPIFactory),
+		authAPIFactory:     authAPIFactory,
+		tokenCache:         newTokenCache(logger),
+		httpClients:        newHTTPClientCache(),
+		zoneCache:          newZoneCache(),
+		recordPollInterval: recordPollInterval,
+		getenv:             os.Getenv,
+		lookupCNAME:        net.DefaultResolver.LookupCNAME,
+		logger:             logger,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 type ionosCloudDnsProviderResolver struct {
-	k8ClientFactory K8ClientFactory
-	namespace       string
-	dnsAPIFactory   DNSAPIFactory
-	k8Client        K8Client
-	generateToken   GenerateTokenFunc
-	logger          *zap.Logger
+	// ctx is cancelled when the webhook shuts down, and is the parent of the contexts of the API calls.
+	ctx                  context.Context
+	cancel               context.CancelFunc
+	k8ClientFactory      K8ClientFactory
+	namespace            string
+	dnsAPIs              *dnsAPICache
+	k8Client             K8Client
+	secretInformerConfig *secretInformerConfig
+	secretInformer       *secretInformer
+	authAPIFactory       AuthAPIFactory
+	tokenCache           *tokenCache
+	execCommands         [][]string
+	credentialsFileDirs  []string
+	vaultAPI             vault.VaultAPI
+	vaultAllowedPaths    []string
+	allowedAPIURLs       []string
+	zoneCreationSuffixes []string
+	zoneCache            *zoneCache
+	httpClients          *httpClientCache
+	// recordProvisioningTimeout and recordDeletionTimeout bound the waits for the provisioning state of records.
+	recordProvisioningTimeout time.Duration
+	recordDeletionTimeout     time.Duration
+	recordPollInterval        time.Duration
+	getenv                    func(string) string
+	lookupCNAME               func(ctx context.Context, host string) (string, error)
+	logger                    *zap.Logger
+	// clusterResourceNamespace is the namespace of the challenges of ClusterIssuers, the namespace of the webhook if
+	// empty.
+	clusterResourceNamespace string
+	// secretAccessClusterWide and secretAccessNamespaces are where the webhook may read secrets besides its namespace.
+	secretAccessClusterWide bool
+	secretAccessNamespaces  []string
+	shutdownOnce            sync.Once
 }
 
 // Name is used as the name for this DNS solver when referencing it on the ACME
@@ -85,16 +247,34 @@ func (s *ionosCloudDnsProviderResolver) Present(ch *v1alpha1.ChallengeRequest) e
 		zap.String("dnsName", ch.DNSName), zap.String("resolvedZone", ch.ResolvedZone), zap.String("resolvedFQDN",
 			ch.ResolvedFQDN))
 
-	dnsAPI, err := s.newDNSAPIFromK8Secret(ch.Config)
+	config, err := loadSolverConfig(ch)
 	if err != nil {
 		return fmt.Errorf("failed to create IONOS Cloud API client: %w", err)
 	}
-
-	zoneId, err := s.findZone(ch, true, dnsAPI)
+	ctx, cancel := context.WithTimeout(s.ctx, requestTimeout)
+	defer cancel()
+	dnsAPI, auth, err := s.newDNSAPI(ctx, ch, config)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to create IONOS Cloud API client: %w", err)
+	}
+	if config.ValidationZone != "" {
+		delegated := validationChallenge(ch, config.ValidationZone)
+		if err := s.checkDelegation(ctx, ch, delegated); err != nil {
+			return err
+		}
+		ch = delegated
 	}
-	return s.findOrCreateRecord(ch, zoneId, dnsAPI)
+	err = s.withCachedZone(auth.source, func() (dnsZone, error) {
+		zone, err := s.findZone(ctx, ch, config.zoneConfig, auth.source, !config.CreateZoneIfMissing, dnsAPI)
+		if err == nil && zone.id == "" {
+			zone, err = s.createZone(ctx, ch, config.zoneConfig, auth.source, dnsAPI)
+		}
+		if err == nil {
+			err = s.findOrCreateRecord(ctx, ch, zone, *config.TTL, dnsAPI)
+		}
+		return zone, err
+	})
+	return s.handleAPIError(auth, err)
 }
 
 // CleanUp should delete the relevant TXT record from the DNS provider console.
@@ -104,20 +284,56 @@ func (s *ionosCloudDnsProviderResolver) Present(ch *v1alpha1.ChallengeRequest) e
 // This is in order to facilitate multiple DNS validations for the same domain
 // concurrently.
 func (s *ionosCloudDnsProviderResolver) CleanUp(ch *v1alpha1.ChallengeRequest) error {
-	dnsAPI, err := s.newDNSAPIFromK8Secret(ch.Config)
+	config, err := loadSolverConfig(ch)
 	if err != nil {
 		return fmt.Errorf("failed to create IONOS Cloud API client: %w", err)
 	}
-
-	zoneId, err := s.findZone(ch, false, dnsAPI)
+	ctx, cancel := context.WithTimeout(s.ctx, requestTimeout)
+	defer cancel()
+	dnsAPI, auth, err := s.newDNSAPI(ctx, ch, config)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to create IONOS Cloud API client: %w", err)
+	}
+	if config.ValidationZone != "" {
+		ch = validationChallenge(ch, config.ValidationZone)
 	}
-	if zoneId == "" {
-		s.logger.Info("zone not found, nothing to clean up", zap.String("zoneName", ch.ResolvedZone))
+	err = s.withCachedZone(auth.source, func() (dnsZone, error) {
+		zone, err := s.findZone(ctx, ch, config.zoneConfig, auth.source, false, dnsAPI)
+		if err != nil {
+			return zone, err
+		}
+		if zone.id == "" {
+			s.logger.Info("zone not found, nothing to clean up", zap.String("fqdn", ch.ResolvedFQDN))
+			return zone, nil
+		}
+		err = s.deleteRecord(ctx, ch, zone, dnsAPI)
+		if err == nil && config.DeleteCreatedZone {
+			err = s.deleteCreatedZone(ctx, auth.source, zone, dnsAPI)
+		}
+		return zone, err
+	})
+	return s.handleAPIError(auth, err)
+}
+
+// handleAPIError adds a hint on how to resolve the error of an IONOS Cloud DNS API call. If the credentials were
+// rejected, the token, the client and the zones cached for the credentials source are dropped, so that the next attempt obtains
+// a new token.
+func (s *ionosCloudDnsProviderResolver) handleAPIError(auth apiCredentials, err error) error {
+	switch {
+	case err == nil:
 		return nil
+	case clouddns.IsUnauthorized(err):
+		s.tokenCache.invalidate(auth.source, auth.token)
+		s.dnsAPIs.evict(auth.source)
+		s.zoneCache.evictSource(auth.source)
+		return fmt.Errorf("%w: the IONOS Cloud credentials were rejected, check that they are valid and not expired", err)
+	case clouddns.IsForbidden(err):
+		return fmt.Errorf("%w: the IONOS Cloud user is not allowed to manage the DNS zone, check its privileges", err)
+	case clouddns.IsThrottled(err):
+		return fmt.Errorf("%w: the rate limit of the IONOS Cloud DNS API was exceeded, the challenge is retried later", err)
+	default:
+		return err
 	}
-	return s.deleteRecord(ch, zoneId, dnsAPI)
 }
 
 // Initialize will be called when the webhook first starts.
@@ -129,6 +345,7 @@ func (s *ionosCloudDnsProviderResolver) CleanUp(ch *v1alpha1.ChallengeRequest) e
 // provider accounts.
 // The stopCh can be used to handle early termination of the webhook, in cases
 // where a SIGTERM or similar signal is sent to the webhook process.
+// If enabled, the secret informer is started here. On termination, the resolver is shut down.
 func (s *ionosCloudDnsProviderResolver) Initialize(kubeClientConfig *rest.Config, stopCh <-chan struct{}) error {
 	s.logger.Info("IONOS Cloud resolver initialized")
 	k8Client, err := s.k8ClientFactory(kubeClientConfig)
@@ -136,70 +353,126 @@ func (s *ionosCloudDnsProviderResolver) Initialize(kubeClientConfig *rest.Config
 		return fmt.Errorf("failed to create k8 client: %w", err)
 	}
 	s.k8Client = k8Client
+	if s.secretInformerConfig != nil {
+		namespace := s.namespace
+		if s.secretInformerConfig.allNamespaces {
+			if s.secretInformerConfig.labelSelector == "" {
+				return fmt.Errorf("a secret label selector is required to watch the secrets of all namespaces")
+			}
+			namespace = v1.NamespaceAll
+		}
+		informer, err := newSecretInformer(k8Client, namespace, s.secretInformerConfig.labelSelector, s.onSecretChanged)
+		if err != nil {
+			return err
+		}
+		informer.run(stopCh)
+		s.secretInformer = informer
+	}
+	if stopCh != nil {
+		go func() {
+			<-stopCh
+			s.Shutdown()
+		}()
+	}
 	return nil
 }
 
-func (s *ionosCloudDnsProviderResolver) findZone(ch *v1alpha1.ChallengeRequest, shouldFind bool, client clouddns.DNSAPI) (string, error) {
-	// fetch zone
-	zoneName := zoneNameFromChallenge(ch)
-	s.logger.Debug("find zone...", zap.String("zoneName", zoneName))
-	zoneList, err := client.GetZones(zoneName)
-	if err != nil {
-		s.logger.Error("Error fetching zone", zap.Error(err))
-		return "", err
-	}
-	for _, zone := range *zoneList.Items {
-		if *zone.Properties.ZoneName == zoneName {
-			s.logger.Info("zone found", zap.String("zoneName", zoneName), zap.String("zoneId", *zone.Id))
-			return *zone.Id, nil
-		}
-	}
+// Shutdown cancels in-flight API calls and deletes the tokens generated by the webhook, within tokenRevokeTimeout.
+// As the webhook server does not wait for its solvers to stop, Shutdown must also be called before the process
+// exits; it returns once the shutdown started on termination is done.
+func (s *ionosCloudDnsProviderResolver) Shutdown() {
+	s.shutdownOnce.Do(func() {
+		s.cancel()
+		s.logger.Info("IONOS Cloud resolver stopping, deleting generated tokens")
+		s.tokenCache.revokeAll()
+	})
+}
 
-	if shouldFind {
-		return "", fmt.Errorf("zone '%s' not found", zoneName)
-	}
-	return "", nil
+// onSecretChanged invalidates the state derived from a secret, when the secret is updated or deleted.
+func (s *ionosCloudDnsProviderResolver) onSecretChanged(key string) {
+	s.logger.Info("credentials secret changed, invalidating cached token", zap.String("secret", key))
+	s.tokenCache.retire(key)
+	s.dnsAPIs.evict(key)
+	s.zoneCache.evictSource(key)
 }
 
-func (s *ionosCloudDnsProviderResolver) findOrCreateRecord(ch *v1alpha1.ChallengeRequest, zoneId string, client clouddns.DNSAPI) error {
-	recordName := recordNameFromChallenge(ch)
+func (s *ionosCloudDnsProviderResolver) findOrCreateRecord(ctx context.Context, ch *v1alpha1.ChallengeRequest, zone dnsZone, ttl int32,
+	client clouddns.DNSAPI,
+) error {
+	zoneId := zone.id
+	recordName := recordNameFromChallenge(ch, zone.name)
 	s.logger.Debug("find txt record...", zap.String("recordName", recordName), zap.String("fqdn", ch.ResolvedFQDN),
 		zap.String("zoneId", zoneId))
-	recordList, err := client.GetRecords(zoneId, recordName)
+	callCtx, cancel := context.WithTimeout(ctx, apiCallTimeout)
+	recordList, err := client.ListRecords(callCtx, zoneId, txtRecordFilter(recordName))
+	cancel()
 	if err != nil {
 		s.logger.Error("Error fetching record", zap.Error(err))
 		return err
 	}
-	// check if record already exists
+	// check if record already exists, a FAILED record is never provisioned and is replaced
 	for _, r := range *recordList.Items {
 		content := r.GetProperties().GetContent()
-		if content != nil && *content == ch.Key {
+		if content == nil || *content != ch.Key {
+			continue
+		}
+		if recordState(r) != ionoscloud.PROVISIONINGSTATE_FAILED {
 			s.logger.Info("record for dns challenge already exists", zap.String("recordId", *r.Id),
 				zap.String("recordName", recordName), zap.String("zoneId", zoneId))
-			return nil
+			if recordState(r) == ionoscloud.PROVISIONINGSTATE_AVAILABLE {
+				return nil
+			}
+			return s.waitForRecordAvailable(ctx, client, zoneId, *r.Id)
+		}
+		s.logger.Warn("record for dns challenge failed, replacing it", zap.String("recordId", *r.Id),
+			zap.String("recordName", recordName), zap.String("zoneId", zoneId))
+		callCtx, cancel := context.WithTimeout(ctx, apiCallTimeout)
+		err := client.DeleteRecord(callCtx, zoneId, *r.Id)
+		cancel()
+		if err != nil && !clouddns.IsNotFound(err) {
+			s.logger.Error("Error deleting failed record", zap.Error(err))
+			return err
+		}
+		if err := s.waitForRecordDeleted(ctx, client, zoneId, *r.Id); err != nil {
+			return err
 		}
 	}
 	s.logger.Debug("record not found, try to create record...", zap.String("recordName", recordName), zap.String("key", ch.Key),
 		zap.String("zoneId", zoneId))
-	record, err := client.CreateTXTRecord(zoneId, recordName, ch.Key)
+	callCtx, cancel = context.WithTimeout(ctx, apiCallTimeout)
+	record, err := client.CreateRecord(callCtx, zoneId, clouddns.NewTXTRecord(recordName, ch.Key, ttl))
+	cancel()
 	if err != nil {
 		s.logger.Error("Error creating record", zap.Error(err))
 		return err
 	}
 	s.logger.Info("record for dns challenge successfully created", zap.String("recordId", *record.Id),
 		zap.String("recordName", recordName), zap.String("zoneId", zoneId))
-	return nil
+	return s.waitForRecordAvailable(ctx, client, zoneId, *record.Id)
 }
 
-func (s *ionosCloudDnsProviderResolver) deleteRecord(ch *v1alpha1.ChallengeRequest, zoneId string, client clouddns.DNSAPI) error {
-	recordName := recordNameFromChallenge(ch)
+func (s *ionosCloudDnsProviderResolver) deleteRecord(ctx context.Context, ch *v1alpha1.ChallengeRequest, zone dnsZone, client clouddns.DNSAPI) error {
+	zoneId := zone.id
+	recordName := recordNameFromChallenge(ch, zone.name)
 	s.logger.Debug("try to find txt record...", zap.String("recordName", recordName), zap.String("fqdn", ch.ResolvedFQDN), zap.String("zoneId", zoneId))
-	recordList, err := client.GetRecords(zoneId, recordName)
+	callCtx, cancel := context.WithTimeout(ctx, apiCallTimeout)
+	recordList, err := client.ListRecords(callCtx, zoneId, txtRecordFilter(recordName))
+	cancel()
 	if err != nil {
 		s.logger.Error("Error fetching record", zap.Error(err))
 		return err
 	}
 	if recordList.Items == nil || len(*recordList.Items) == 0 {
+		// the records of a deleted zone are listed as empty, so a cached zone is confirmed to still exist, which
+		// lets withCachedZone look it up again otherwise
+		if zone.cached {
+			callCtx, cancel = context.WithTimeout(ctx, apiCallTimeout)
+			_, err = client.GetZone(callCtx, zoneId)
+			cancel()
+			if err != nil {
+				return err
+			}
+		}
 		s.logger.Info("no record with that name found, nothing to clean up", zap.String("recordName", recordName),
 			zap.String("zoneId", zoneId))
 		return nil
@@ -218,98 +491,126 @@ func (s *ionosCloudDnsProviderResolver) deleteRecord(ch *v1alpha1.ChallengeReque
 		return nil
 	}
 	s.logger.Info("record found, deleting...", zap.String("recordName", recordName), zap.String("recordId", *record.Id))
-	err = client.DeleteRecord(zoneId, *record.Id)
+	callCtx, cancel = context.WithTimeout(ctx, apiCallTimeout)
+	err = client.DeleteRecord(callCtx, zoneId, *record.Id)
+	cancel()
 	if err != nil {
 		s.logger.Error("Error deleting record", zap.Error(err))
 		return err
 	}
 	s.logger.Info("record successfully deleted", zap.String("recordId", *record.Id), zap.String("recordName", recordName),
 		zap.String("zoneId", zoneId))
-	return nil
+	return s.waitForRecordDeleted(ctx, client, zoneId, *record.Id)
 }
 
-func (s *ionosCloudDnsProviderResolver) newDNSAPIFromK8Secret(
-	challengeConfig *apiextensionsv1.JSON,
-) (clouddns.DNSAPI, error) {
+// loadSolverConfig parses the solver config of the challenge, validates it and applies the defaults.
+func loadSolverConfig(ch *v1alpha1.ChallengeRequest) (ionosCloudDNS01SolverConfig, error) {
 	var config ionosCloudDNS01SolverConfig
 
-	if challengeConfig != nil && len(challengeConfig.Raw) > 0 {
-		if err := json.Unmarshal(challengeConfig.Raw, &config); err != nil {
-			return nil, fmt.Errorf("failed to parse config: %w", err)
+	if ch.Config != nil && len(ch.Config.Raw) > 0 {
+		if err := json.Unmarshal(ch.Config.Raw, &config); err != nil {
+			return ionosCloudDNS01SolverConfig{}, fmt.Errorf("failed to parse config: %w", err)
 		}
 	}
 
-	if config.SecretRef == "" {
-		config.SecretRef = defaultSecretName
+	if config.TTL == nil {
+		ttl := int32(defaultRecordTTL)
+		config.TTL = &ttl
 	}
-
-	if config.AuthTokenSecretKey == "" {
-		config.AuthTokenSecretKey = defaultAuthTokenSecretKey
+	if *config.TTL < minRecordTTL || *config.TTL > maxRecordTTL {
+		return ionosCloudDNS01SolverConfig{}, fmt.Errorf("invalid ttl %d: must be between %d and %d seconds",
+			*config.TTL, minRecordTTL, maxRecordTTL)
+	}
+	config.ZoneName = strings.ToLower(strings.TrimSuffix(config.ZoneName, "."))
+	config.ValidationZone = strings.ToLower(strings.TrimSuffix(config.ValidationZone, "."))
+	if config.ValidationZone != "" {
+		if config.ZoneName != "" && config.ZoneName != config.ValidationZone {
+			return ionosCloudDNS01SolverConfig{}, fmt.Errorf("zoneName '%s' differs from validationZone '%s'",
+				config.ZoneName, config.ValidationZone)
+		}
+		config.ZoneName = config.ValidationZone
 	}
+	return config, nil
+}
+
+// apiCredentials identifies the credentials a DNS API client was created with: the source of the credentials and
+// the token obtained from them.
+type apiCredentials struct {
+	source string
+	token  string
+}
 
-	if config.UsernameSecretKey == "" {
-		config.UsernameSecretKey = defaultUsernameSecretKey
+// newDNSAPI creates the DNS API client with the credentials for the challenge. It also returns the credentials the
+// client uses.
+func (s *ionosCloudDnsProviderResolver) newDNSAPI(ctx context.Context,
+	ch *v1alpha1.ChallengeRequest, config ionosCloudDNS01SolverConfig,
+) (clouddns.DNSAPI, apiCredentials, error) {
+	dnsAPIConfig, authAPIConfig, err := s.apiConfigs(config.endpointsConfig)
+	if err != nil {
+		return nil, apiCredentials{}, err
 	}
 
-	if config.PasswordSecretKey == "" {
-		config.PasswordSecretKey = defaultPasswordSecretKey
+	credentialsConfig, err := credentialsConfigFor(ch, config)
+	if err != nil {
+		return nil, apiCredentials{}, err
 	}
 
-	secret, err := s.k8Client.CoreV1().Secrets(s.namespace).Get(context.Background(), config.SecretRef, v1.GetOptions{})
+	creds, err := s.resolveCredentials(ctx, ch, credentialsConfig)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get secret %s from namespace %s: %w", config.SecretRef, s.namespace, err)
+		return nil, apiCredentials{}, err
 	}
 
-	token := string(secret.Data[config.AuthTokenSecretKey])
+	token, err := s.tokenFromCredentials(ctx, creds, authAPIConfig)
+	if err != nil {
+		return nil, apiCredentials{}, err
+	}
 
-	if token == "" {
-		username := string(secret.Data[config.UsernameSecretKey])
-		password := string(secret.Data[config.PasswordSecretKey])
-		if username == "" || password == "" {
-			return nil, fmt.Errorf("empty username or password: a valid username-password pair should be provided when the token is not provided")
-		}
-		s.logger.Info("token not provided, attempting to authenticate using username and password")
-		configuration := ionoscloud_auth.NewConfiguration(string(secret.Data[config.UsernameSecretKey]),
-			string(secret.Data[config.PasswordSecretKey]), "", "")
-		token, err = s.generateToken(configuration)
-		if err != nil {
-			return nil, fmt.Errorf("failed generate token: %w", err)
-		}
+	// the rate limit applies to the contract, which may be shared by several credentials
+	dnsAPIConfig.Account = creds.source
+	if contract, ok := contractNumber(token); ok {
+		dnsAPIConfig.Account = "contract/" + contract
 	}
+	return s.dnsAPIs.get(creds.source, token, dnsAPIConfig), apiCredentials{source: creds.source, token: token}, nil
+}
 
-	return s.dnsAPIFactory(token), nil
+// recordNameFromChallenge returns the name of the challenge record relative to the given zone.
+func recordNameFromChallenge(ch *v1alpha1.ChallengeRequest, zoneName string) string {
+	fqdn := strings.TrimSuffix(ch.ResolvedFQDN, ".")
+	if len(fqdn) > len(zoneName) && strings.EqualFold(fqdn[len(fqdn)-len(zoneName)-1:], "."+zoneName) {
+		return fqdn[:len(fqdn)-len(zoneName)-1]
+	}
+	return fqdn
 }
 
-func recordNameFromChallenge(ch *v1alpha1.ChallengeRequest) string {
-	return strings.TrimSuffix(ch.ResolvedFQDN, "."+ch.ResolvedZone)
+func txtRecordFilter(recordName string) clouddns.RecordFilter {
+	return clouddns.RecordFilter{Name: recordName, Type: ionoscloud.RECORDTYPE_TXT}
 }
 
 func zoneNameFromChallenge(ch *v1alpha1.ChallengeRequest) string {
 	return strings.TrimSuffix(ch.ResolvedZone, ".")
 }
 
-func DefaultDNSAPIFactory(token string) clouddns.DNSAPI {
-	return clouddns.CreateDNSAPI(
-		ionoscloud.NewAPIClient(
-			ionoscloud.NewConfiguration("", "", token, ""),
-		),
-	)
+func DefaultDNSAPIFactory(token string, config APIConfig) clouddns.DNSAPI {
+	return NewDNSAPIFactory(clouddns.DefaultRetryPolicy, nil)(token, config)
+}
+
+// NewDNSAPIFactory returns a factory for DNS API clients retrying failed calls according to the retry policy. The
+// calls of the clients of an account share the rate limiter of the account, unless rateLimiters is nil.
+func NewDNSAPIFactory(retryPolicy clouddns.RetryPolicy, rateLimiters *clouddns.RateLimiters) DNSAPIFactory {
+	return func(token string, config APIConfig) clouddns.DNSAPI {
+		clientConfig := ionoscloud.NewConfiguration("", "", token, config.URL)
+		clientConfig.HTTPClient = config.HTTPClient
+		return clouddns.CreateDNSAPI(ionoscloud.NewAPIClient(clientConfig), retryPolicy,
+			rateLimiters.Get(config.Account))
+	}
 }
 
 func DefaultK8FactoryFactory(config *rest.Config) (K8Client, error) {
 	return kubernetes.NewForConfig(config)
 }
 
-func DefaultGenerateTokenFunc(cfg *ionoscloud_auth.Configuration) (string, error) {
-	apiClient := ionoscloud_auth.NewAPIClient(cfg)
-	jwtToken, _, err := apiClient.TokensApi.TokensGenerate(context.Background()).Ttl(3600).Execute()
-	if err != nil {
-		return "", fmt.Errorf("failed to obtain token from IONOS Cloud Auth API: %w", err)
-	}
-
-	if jwtToken.Token == nil || *jwtToken.Token == "" {
-		return "", fmt.Errorf("unexpected response from IONOS Cloud Auth API")
-	}
-
-	return *jwtToken.Token, nil
+func DefaultAuthAPIFactory(username, password string, config APIConfig) cloudauth.AuthAPI {
+	clientConfig := ionoscloud_auth.NewConfiguration(username, password, "", config.URL)
+	clientConfig.HTTPClient = config.HTTPClient
+	return cloudauth.CreateAuthAPI(ionoscloud_auth.NewAPIClient(clientConfig))
 }
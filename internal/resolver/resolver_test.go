@@ -6,22 +6,32 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/ionos-cloud/cert-manager-webhook-ionos-cloud/internal/cloudauth"
 	"github.com/ionos-cloud/cert-manager-webhook-ionos-cloud/internal/clouddns"
+	"github.com/ionos-cloud/cert-manager-webhook-ionos-cloud/internal/metrics"
 	"github.com/ionos-cloud/cert-manager-webhook-ionos-cloud/internal/mocks"
-	ionoscloud_auth "github.com/ionos-cloud/sdk-go-auth"
 	dnsclient "github.com/ionos-cloud/sdk-go-dns"
 
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/rest"
+	"k8s.io/component-base/metrics/testutil"
 )
 
 var (
@@ -47,9 +57,10 @@ const testNamespace = "unit-test"
 
 type ResolverTestSuite struct {
 	suite.Suite
-	dnsAPIMock *mocks.DNSAPI
-	k8Client   *mocks.K8Client
-	logger     *zap.Logger
+	dnsAPIMock  *mocks.DNSAPI
+	authAPIMock *mocks.AuthAPI
+	k8Client    *mocks.K8Client
+	logger      *zap.Logger
 }
 
 func TestSuite(t *testing.T) {
@@ -64,6 +75,7 @@ func (s *ResolverTestSuite) SetupSuite() {
 
 func (s *ResolverTestSuite) setupMocks() {
 	s.dnsAPIMock = mocks.NewDNSAPI(s.T())
+	s.authAPIMock = mocks.NewAuthAPI(s.T())
 	s.logger.Debug("apiClient with mocks is created")
 	s.k8Client = mocks.NewK8Client(s.T())
 }
@@ -83,6 +95,8 @@ func (s *ResolverTestSuite) TestPresent() {
 		thenError              string
 		whenConfigParseError   bool
 		thenRecordCreateKey    string
+		thenRecordDeleteId     string
+		thenRecordTTL          int32
 	}{
 		{
 			name:       "invalid config json",
@@ -164,7 +178,7 @@ func (s *ResolverTestSuite) TestPresent() {
 				ResolvedFQDN: "_acme-challenge.test.com.",
 			},
 			whenK8SecretContent: secretDataWithToken,
-			thenError:           "zone 'test.com' not found",
+			thenError:           "no zone found for '_acme-challenge.test.com'",
 		},
 		{
 			name: "zone already exists",
@@ -188,6 +202,44 @@ func (s *ResolverTestSuite) TestPresent() {
 			whenK8SecretContent: secretDataWithToken,
 			thenRecordCreateKey: "test-key",
 		},
+		{
+			name: "record is created with the configured ttl",
+			givenZones: []dnsclient.ZoneRead{
+				{
+					Id: toPTR("test-zone-id"),
+					Properties: &dnsclient.Zone{
+						ZoneName: toPTR("test.com"),
+					},
+					Type: toPTR("NATIVE"),
+				},
+			},
+			givenRecords: []dnsclient.RecordRead{},
+			whenChallenge: &v1alpha1.ChallengeRequest{
+				UID:          "test-UID",
+				Key:          "test-key",
+				DNSName:      "*.test.com",
+				ResolvedZone: "test.com.",
+				ResolvedFQDN: "_acme-challenge.test.com.",
+				Config:       &apiextensionsv1.JSON{Raw: []byte(`{"ttl":300}`)},
+			},
+			whenK8SecretContent: secretDataWithToken,
+			thenRecordCreateKey: "test-key",
+			thenRecordTTL:       300,
+		},
+		{
+			name:       "ttl below the limit",
+			givenZones: []dnsclient.ZoneRead{},
+			whenChallenge: &v1alpha1.ChallengeRequest{
+				UID:          "test-UID",
+				Key:          "test-key",
+				DNSName:      "*.test.com",
+				ResolvedZone: "test.com.",
+				ResolvedFQDN: "_acme-challenge.test.com.",
+				Config:       &apiextensionsv1.JSON{Raw: []byte(`{"ttl":30}`)},
+			},
+			whenConfigParseError: true,
+			thenError:            "failed to create IONOS Cloud API client: invalid ttl 30: must be between 60 and 86400 seconds",
+		},
 		{
 			name: "record with the same name and key already exists",
 			givenZones: []dnsclient.ZoneRead{
@@ -219,6 +271,39 @@ func (s *ResolverTestSuite) TestPresent() {
 			whenK8SecretContent: secretDataWithToken,
 			thenRecordCreateKey: "", // no record should be created
 		},
+		{
+			name: "failed record with the same name and key is replaced",
+			givenZones: []dnsclient.ZoneRead{
+				{
+					Id: toPTR("test-zone-id"),
+					Properties: &dnsclient.Zone{
+						ZoneName: toPTR("test.com"),
+					},
+					Type: toPTR("NATIVE"),
+				},
+			},
+			givenRecords: []dnsclient.RecordRead{
+				{
+					Id: toPTR("failed-record-id"),
+					Properties: &dnsclient.Record{
+						Name:    toPTR("_acme-challenge"),
+						Type:    typeTxtRecord,
+						Content: toPTR("test-key"),
+					},
+					Metadata: &dnsclient.MetadataWithStateFqdnZoneId{State: toPTR(dnsclient.PROVISIONINGSTATE_FAILED)},
+				},
+			},
+			whenChallenge: &v1alpha1.ChallengeRequest{
+				UID:          "test-UID",
+				Key:          "test-key",
+				DNSName:      "*.test.com",
+				ResolvedZone: "test.com.",
+				ResolvedFQDN: "_acme-challenge.test.com.",
+			},
+			whenK8SecretContent: secretDataWithToken,
+			thenRecordDeleteId:  "failed-record-id",
+			thenRecordCreateKey: "test-key",
+		},
 		{
 			name: "record with the same name but different key already exists",
 			givenZones: []dnsclient.ZoneRead{
@@ -324,24 +409,33 @@ func (s *ResolverTestSuite) TestPresent() {
 					zoneReadList := dnsclient.ZoneReadList{
 						Items: &tc.givenZones,
 					}
-					s.dnsAPIMock.EXPECT().GetZones(zoneName).Return(zoneReadList, tc.whenZonesReadError)
+					s.dnsAPIMock.EXPECT().GetZones(mock.Anything, zoneName).Return(zoneReadList, tc.whenZonesReadError)
 				}
 				if tc.givenRecords != nil {
 					recordName := strings.TrimSuffix(tc.whenChallenge.ResolvedFQDN, "."+tc.whenChallenge.ResolvedZone)
-					s.dnsAPIMock.EXPECT().GetRecords("test-zone-id", recordName).Return(dnsclient.RecordReadList{
+					s.dnsAPIMock.EXPECT().ListRecords(mock.Anything, "test-zone-id", txtRecordFilter(recordName)).Return(dnsclient.RecordReadList{
 						Items: &tc.givenRecords,
 					}, tc.whenRecordsReadError)
 				}
+				if tc.thenRecordDeleteId != "" {
+					s.dnsAPIMock.EXPECT().DeleteRecord(mock.Anything, "test-zone-id", tc.thenRecordDeleteId).Return(nil).Once()
+				}
 				if tc.thenRecordCreateKey != "" {
-					s.dnsAPIMock.EXPECT().CreateTXTRecord("test-zone-id", "_acme-challenge", tc.thenRecordCreateKey).
+					ttl := tc.thenRecordTTL
+					if ttl == 0 {
+						ttl = defaultRecordTTL
+					}
+					s.dnsAPIMock.EXPECT().CreateRecord(mock.Anything, "test-zone-id", clouddns.NewTXTRecord("_acme-challenge", tc.thenRecordCreateKey, ttl)).
 						Return(dnsclient.RecordRead{
 							Id: toPTR("test-record-id"),
 						}, tc.whenRecordCreateError)
 				}
 			}
 
+			s.authAPIMock.EXPECT().GenerateToken(mock.Anything, int32(3600)).Return("token", tc.whenTokenGenerateError).Maybe()
+
 			resolver := NewResolver(testNamespace, createTestK8Factory(s.k8Client),
-				createTestDNSFactory(s.dnsAPIMock), createTestGenerateTokenFunc(tc.whenTokenGenerateError), s.logger)
+				createTestDNSFactory(s.dnsAPIMock), createTestAuthAPIFactory(s.authAPIMock), s.logger)
 			resolver.Initialize(&rest.Config{}, nil)
 			err := resolver.Present(tc.whenChallenge)
 			if tc.thenError != "" {
@@ -620,23 +714,25 @@ func (s *ResolverTestSuite) TestCleanUp() {
 					zoneReadList := dnsclient.ZoneReadList{
 						Items: &tc.givenZones,
 					}
-					s.dnsAPIMock.EXPECT().GetZones(zoneName).Return(zoneReadList, tc.whenZonesReadError)
+					s.dnsAPIMock.EXPECT().GetZones(mock.Anything, zoneName).Return(zoneReadList, tc.whenZonesReadError)
 					if len(tc.givenZones) > 0 {
 						zoneId := *tc.givenZones[0].GetId()
 						if tc.givenRecords != nil {
 							recordName := strings.TrimSuffix(tc.whenChallenge.ResolvedFQDN, "."+tc.whenChallenge.ResolvedZone)
-							s.dnsAPIMock.EXPECT().GetRecords(zoneId, recordName).Return(dnsclient.RecordReadList{
+							s.dnsAPIMock.EXPECT().ListRecords(mock.Anything, zoneId, txtRecordFilter(recordName)).Return(dnsclient.RecordReadList{
 								Items: &tc.givenRecords,
 							}, tc.whenRecordsReadError)
 						}
 						if tc.thenDeleteRecordId != "" {
-							s.dnsAPIMock.EXPECT().DeleteRecord(zoneId, tc.thenDeleteRecordId).Return(tc.whenRecordDeleteError)
+							s.dnsAPIMock.EXPECT().DeleteRecord(mock.Anything, zoneId, tc.thenDeleteRecordId).Return(tc.whenRecordDeleteError)
 						}
 					}
 				}
 			}
 
-			resolver := NewResolver(testNamespace, createTestK8Factory(s.k8Client), createTestDNSFactory(s.dnsAPIMock), createTestGenerateTokenFunc(tc.whenTokenGenerateError), s.logger)
+			s.authAPIMock.EXPECT().GenerateToken(mock.Anything, int32(3600)).Return("token", tc.whenTokenGenerateError).Maybe()
+
+			resolver := NewResolver(testNamespace, createTestK8Factory(s.k8Client), createTestDNSFactory(s.dnsAPIMock), createTestAuthAPIFactory(s.authAPIMock), s.logger)
 			resolver.Initialize(&rest.Config{}, nil)
 			err := resolver.CleanUp(tc.whenChallenge)
 			if tc.thenError != "" {
@@ -649,8 +745,569 @@ func (s *ResolverTestSuite) TestCleanUp() {
 	}
 }
 
+func (s *ResolverTestSuite) TestGeneratedTokenIsReused() {
+	s.setupMocks()
+	setUpK8ClientExpectations(s.T(), s.k8Client, nil, secretDataWithUsernamePassword)
+	challenge := &v1alpha1.ChallengeRequest{
+		UID:          "test-UID",
+		Key:          "test-key",
+		DNSName:      "*.test.com",
+		ResolvedZone: "test.com.",
+		ResolvedFQDN: "_acme-challenge.test.com.",
+	}
+	s.dnsAPIMock.EXPECT().GetZones(mock.Anything, "test.com").Return(dnsclient.ZoneReadList{Items: &[]dnsclient.ZoneRead{}}, nil)
+	s.authAPIMock.EXPECT().GenerateToken(mock.Anything, int32(3600)).Return("token", nil).Once()
+
+	resolver := NewResolver(testNamespace, createTestK8Factory(s.k8Client), createTestDNSFactory(s.dnsAPIMock),
+		createTestAuthAPIFactory(s.authAPIMock), s.logger)
+	require.NoError(s.T(), resolver.Initialize(&rest.Config{}, nil))
+	require.NoError(s.T(), resolver.CleanUp(challenge))
+	require.NoError(s.T(), resolver.CleanUp(challenge))
+}
+
+func (s *ResolverTestSuite) TestGeneratedTokensAreDeletedOnShutdown() {
+	s.setupMocks()
+	setUpK8ClientExpectations(s.T(), s.k8Client, nil, secretDataWithUsernamePassword)
+	challenge := &v1alpha1.ChallengeRequest{
+		UID:          "test-UID",
+		Key:          "test-key",
+		DNSName:      "*.test.com",
+		ResolvedZone: "test.com.",
+		ResolvedFQDN: "_acme-challenge.test.com.",
+	}
+	s.dnsAPIMock.EXPECT().GetZones(mock.Anything, "test.com").Return(dnsclient.ZoneReadList{Items: &[]dnsclient.ZoneRead{}}, nil)
+	s.authAPIMock.EXPECT().GenerateToken(mock.Anything, int32(3600)).Return(testJWTWithID("test-token-id", time.Now().Add(time.Hour)), nil)
+	deleted := make(chan struct{})
+	s.authAPIMock.EXPECT().DeleteToken(mock.Anything, "test-token-id").Return(nil).Run(func(_ context.Context, _ string) { close(deleted) })
+
+	stopCh := make(chan struct{})
+	resolver := NewResolver(testNamespace, createTestK8Factory(s.k8Client), createTestDNSFactory(s.dnsAPIMock),
+		createTestAuthAPIFactory(s.authAPIMock), s.logger)
+	require.NoError(s.T(), resolver.Initialize(&rest.Config{}, stopCh))
+	require.NoError(s.T(), resolver.CleanUp(challenge))
+	close(stopCh)
+	select {
+	case <-deleted:
+	case <-time.After(5 * time.Second):
+		s.T().Fatal("token was not deleted on shutdown")
+	}
+}
+
+func (s *ResolverTestSuite) TestShutdownWaitsForTokenDeletion() {
+	s.setupMocks()
+	setUpK8ClientExpectations(s.T(), s.k8Client, nil, secretDataWithUsernamePassword)
+	challenge := &v1alpha1.ChallengeRequest{
+		UID:          "test-UID",
+		Key:          "test-key",
+		DNSName:      "*.test.com",
+		ResolvedZone: "test.com.",
+		ResolvedFQDN: "_acme-challenge.test.com.",
+	}
+	s.dnsAPIMock.EXPECT().GetZones(mock.Anything, "test.com").Return(dnsclient.ZoneReadList{Items: &[]dnsclient.ZoneRead{}}, nil)
+	s.authAPIMock.EXPECT().GenerateToken(mock.Anything, int32(3600)).Return(testJWTWithID("test-token-id", time.Now().Add(time.Hour)), nil)
+	var deleted atomic.Bool
+	s.authAPIMock.EXPECT().DeleteToken(mock.Anything, "test-token-id").Return(nil).Run(func(_ context.Context, _ string) {
+		time.Sleep(100 * time.Millisecond)
+		deleted.Store(true)
+	}).Once()
+
+	stopCh := make(chan struct{})
+	resolver := NewResolver(testNamespace, createTestK8Factory(s.k8Client), createTestDNSFactory(s.dnsAPIMock),
+		createTestAuthAPIFactory(s.authAPIMock), s.logger).(*ionosCloudDnsProviderResolver)
+	require.NoError(s.T(), resolver.Initialize(&rest.Config{}, stopCh))
+	require.NoError(s.T(), resolver.CleanUp(challenge))
+	close(stopCh)
+	resolver.Shutdown()
+	require.True(s.T(), deleted.Load(), "Shutdown should return once the token is deleted")
+}
+
+func (s *ResolverTestSuite) TestRejectedTokenIsInvalidated() {
+	s.setupMocks()
+	setUpK8ClientExpectations(s.T(), s.k8Client, nil, secretDataWithUsernamePassword)
+	challenge := &v1alpha1.ChallengeRequest{
+		UID:          "test-UID",
+		Key:          "test-key",
+		DNSName:      "*.test.com",
+		ResolvedZone: "test.com.",
+		ResolvedFQDN: "_acme-challenge.test.com.",
+	}
+	s.dnsAPIMock.EXPECT().GetZones(mock.Anything, "test.com").
+		Return(dnsclient.ZoneReadList{}, &clouddns.APIError{StatusCode: http.StatusUnauthorized}).Once()
+	s.dnsAPIMock.EXPECT().GetZones(mock.Anything, "test.com").Return(dnsclient.ZoneReadList{Items: &[]dnsclient.ZoneRead{}}, nil).Once()
+	s.authAPIMock.EXPECT().GenerateToken(mock.Anything, int32(3600)).Return(testJWTWithID("rejected-token-id", time.Now().Add(time.Hour)), nil).Once()
+	s.authAPIMock.EXPECT().GenerateToken(mock.Anything, int32(3600)).Return(testJWTWithID("new-token-id", time.Now().Add(time.Hour)), nil).Once()
+
+	resolver := NewResolver(testNamespace, createTestK8Factory(s.k8Client), createTestDNSFactory(s.dnsAPIMock),
+		createTestAuthAPIFactory(s.authAPIMock), s.logger)
+	require.NoError(s.T(), resolver.Initialize(&rest.Config{}, nil))
+	err := resolver.CleanUp(challenge)
+	require.True(s.T(), clouddns.IsUnauthorized(err))
+	require.ErrorContains(s.T(), err, "the IONOS Cloud credentials were rejected")
+	require.NoError(s.T(), resolver.CleanUp(challenge))
+}
+
+func (s *ResolverTestSuite) TestCachedZoneIsLookedUpAgain() {
+	s.setupMocks()
+	setUpK8ClientExpectations(s.T(), s.k8Client, nil, secretDataWithToken)
+	challenge := &v1alpha1.ChallengeRequest{
+		UID:          "test-UID",
+		Key:          "test-key",
+		DNSName:      "*.test.com",
+		ResolvedZone: "test.com.",
+		ResolvedFQDN: "_acme-challenge.test.com.",
+	}
+	zones := func(id string) dnsclient.ZoneReadList {
+		return dnsclient.ZoneReadList{Items: &[]dnsclient.ZoneRead{{Id: toPTR(id), Properties: &dnsclient.Zone{ZoneName: toPTR("test.com")}}}}
+	}
+	existing := dnsclient.RecordReadList{Items: &[]dnsclient.RecordRead{{
+		Id:         toPTR("test-record-id"),
+		Properties: &dnsclient.Record{Content: toPTR("test-key")},
+		Metadata:   &dnsclient.MetadataWithStateFqdnZoneId{State: toPTR(dnsclient.PROVISIONINGSTATE_AVAILABLE)},
+	}}}
+	s.dnsAPIMock.EXPECT().GetZones(mock.Anything, "test.com").Return(zones("old-zone-id"), nil).Once()
+	s.dnsAPIMock.EXPECT().ListRecords(mock.Anything, "old-zone-id", txtRecordFilter("_acme-challenge")).Return(existing, nil).Once()
+	s.dnsAPIMock.EXPECT().ListRecords(mock.Anything, "old-zone-id", txtRecordFilter("_acme-challenge")).
+		Return(dnsclient.RecordReadList{}, &clouddns.APIError{StatusCode: http.StatusNotFound}).Once()
+	s.dnsAPIMock.EXPECT().GetZones(mock.Anything, "test.com").Return(zones("new-zone-id"), nil).Once()
+	s.dnsAPIMock.EXPECT().ListRecords(mock.Anything, "new-zone-id", txtRecordFilter("_acme-challenge")).Return(existing, nil).Once()
+
+	resolver := NewResolver(testNamespace, createTestK8Factory(s.k8Client), createTestDNSFactory(s.dnsAPIMock),
+		createTestAuthAPIFactory(s.authAPIMock), s.logger, WithZoneCache(time.Hour, time.Minute))
+	require.NoError(s.T(), resolver.Initialize(&rest.Config{}, nil))
+	// the second call uses the cached zone, which was recreated in the meantime
+	require.NoError(s.T(), resolver.Present(challenge))
+	require.NoError(s.T(), resolver.Present(challenge))
+}
+
+func (s *ResolverTestSuite) TestCachedZoneIsConfirmedInCleanUp() {
+	s.setupMocks()
+	setUpK8ClientExpectations(s.T(), s.k8Client, nil, secretDataWithToken)
+	challenge := &v1alpha1.ChallengeRequest{
+		UID:          "test-UID",
+		Key:          "test-key",
+		DNSName:      "*.test.com",
+		ResolvedZone: "test.com.",
+		ResolvedFQDN: "_acme-challenge.test.com.",
+	}
+	zones := func(id string) dnsclient.ZoneReadList {
+		return dnsclient.ZoneReadList{Items: &[]dnsclient.ZoneRead{{Id: toPTR(id), Properties: &dnsclient.Zone{ZoneName: toPTR("test.com")}}}}
+	}
+	existing := dnsclient.RecordReadList{Items: &[]dnsclient.RecordRead{{
+		Id:         toPTR("test-record-id"),
+		Properties: &dnsclient.Record{Content: toPTR("test-key")},
+	}}}
+	s.dnsAPIMock.EXPECT().GetZones(mock.Anything, "test.com").Return(zones("old-zone-id"), nil).Once()
+	s.dnsAPIMock.EXPECT().ListRecords(mock.Anything, "old-zone-id", txtRecordFilter("_acme-challenge")).Return(existing, nil).Once()
+	s.dnsAPIMock.EXPECT().DeleteRecord(mock.Anything, "old-zone-id", "test-record-id").Return(nil).Once()
+	// the records of the deleted zone are listed as empty
+	s.dnsAPIMock.EXPECT().ListRecords(mock.Anything, "old-zone-id", txtRecordFilter("_acme-challenge")).
+		Return(dnsclient.RecordReadList{Items: &[]dnsclient.RecordRead{}}, nil).Once()
+	s.dnsAPIMock.EXPECT().GetZone(mock.Anything, "old-zone-id").
+		Return(dnsclient.ZoneRead{}, &clouddns.APIError{StatusCode: http.StatusNotFound}).Once()
+	s.dnsAPIMock.EXPECT().GetZones(mock.Anything, "test.com").Return(zones("new-zone-id"), nil).Once()
+	s.dnsAPIMock.EXPECT().ListRecords(mock.Anything, "new-zone-id", txtRecordFilter("_acme-challenge")).Return(existing, nil).Once()
+	s.dnsAPIMock.EXPECT().DeleteRecord(mock.Anything, "new-zone-id", "test-record-id").Return(nil).Once()
+
+	resolver := NewResolver(testNamespace, createTestK8Factory(s.k8Client), createTestDNSFactory(s.dnsAPIMock),
+		createTestAuthAPIFactory(s.authAPIMock), s.logger, WithZoneCache(time.Hour, time.Minute))
+	require.NoError(s.T(), resolver.Initialize(&rest.Config{}, nil))
+	// the second call uses the cached zone, which was recreated in the meantime
+	require.NoError(s.T(), resolver.CleanUp(challenge))
+	require.NoError(s.T(), resolver.CleanUp(challenge))
+}
+
+func (s *ResolverTestSuite) TestValidationZone() {
+	s.setupMocks()
+	setUpK8ClientExpectations(s.T(), s.k8Client, nil, secretDataWithToken)
+	challenge := &v1alpha1.ChallengeRequest{
+		UID:          "test-UID",
+		Key:          "test-key",
+		DNSName:      "www.customer.com",
+		ResolvedZone: "customer.com.",
+		ResolvedFQDN: "_acme-challenge.www.customer.com.",
+		Config:       &apiextensionsv1.JSON{Raw: []byte(`{"validationZone":"acme.example.net."}`)},
+	}
+	s.dnsAPIMock.EXPECT().GetZones(mock.Anything, "acme.example.net").Return(dnsclient.ZoneReadList{Items: &[]dnsclient.ZoneRead{{
+		Id: toPTR("validation-zone-id"), Properties: &dnsclient.Zone{ZoneName: toPTR("acme.example.net")},
+	}}}, nil)
+	s.dnsAPIMock.EXPECT().ListRecords(mock.Anything, "validation-zone-id", txtRecordFilter("www.customer.com")).
+		Return(dnsclient.RecordReadList{Items: &[]dnsclient.RecordRead{}}, nil)
+	s.dnsAPIMock.EXPECT().CreateRecord(mock.Anything, "validation-zone-id", clouddns.NewTXTRecord("www.customer.com", "test-key", defaultRecordTTL)).
+		Return(dnsclient.RecordRead{Id: toPTR("test-record-id")}, nil)
+
+	resolver := NewResolver(testNamespace, createTestK8Factory(s.k8Client), createTestDNSFactory(s.dnsAPIMock),
+		createTestAuthAPIFactory(s.authAPIMock), s.logger).(*ionosCloudDnsProviderResolver)
+	cname := ""
+	resolver.lookupCNAME = func(_ context.Context, _ string) (string, error) { return cname, nil }
+	require.NoError(s.T(), resolver.Initialize(&rest.Config{}, nil))
+
+	err := resolver.Present(challenge)
+	require.EqualError(s.T(), err, "missing CNAME record '_acme-challenge.www.customer.com. CNAME "+
+		"www.customer.com.acme.example.net.' delegating the challenge to the validation zone")
+	cname = "www.customer.com.acme.example.net."
+	require.NoError(s.T(), resolver.Present(challenge))
+}
+
+func (s *ResolverTestSuite) TestShutdownCancelsAPICalls() {
+	s.setupMocks()
+	setUpK8ClientExpectations(s.T(), s.k8Client, nil, secretDataWithToken)
+	challenge := &v1alpha1.ChallengeRequest{
+		UID:          "test-UID",
+		Key:          "test-key",
+		DNSName:      "*.test.com",
+		ResolvedZone: "test.com.",
+		ResolvedFQDN: "_acme-challenge.test.com.",
+	}
+	stopCh := make(chan struct{})
+	s.dnsAPIMock.EXPECT().GetZones(mock.Anything, "test.com").RunAndReturn(
+		func(ctx context.Context, _ string) (dnsclient.ZoneReadList, error) {
+			deadline, ok := ctx.Deadline()
+			require.True(s.T(), ok)
+			require.WithinDuration(s.T(), time.Now().Add(apiCallTimeout), deadline, time.Second)
+			close(stopCh)
+			<-ctx.Done()
+			return dnsclient.ZoneReadList{}, ctx.Err()
+		})
+
+	resolver := NewResolver(testNamespace, createTestK8Factory(s.k8Client), createTestDNSFactory(s.dnsAPIMock),
+		createTestAuthAPIFactory(s.authAPIMock), s.logger)
+	require.NoError(s.T(), resolver.Initialize(&rest.Config{}, stopCh))
+	require.ErrorIs(s.T(), resolver.Present(challenge), context.Canceled)
+}
+
+func (s *ResolverTestSuite) TestSecretNamespace() {
+	errNotFound := k8serrors.NewNotFound(corev1.Resource("secrets"), defaultSecretName)
+	errForbidden := k8serrors.NewForbidden(corev1.Resource("secrets"), defaultSecretName, errK8Client)
+	type secretLookup struct {
+		namespace string
+		name      string
+		err       error
+	}
+	testCases := []struct {
+		name                          string
+		givenClusterResourceNamespace string
+		givenSecretAccess             []string
+		whenResourceNamespace         string
+		whenConfig                    credentialsConfig
+		thenLookups                   []secretLookup
+		thenSecretRef                 string
+		thenError                     string
+	}{
+		{
+			name:          "webhook namespace by default",
+			whenConfig:    credentialsConfig{SecretRef: defaultSecretName},
+			thenLookups:   []secretLookup{{namespace: testNamespace, name: defaultSecretName}},
+			thenSecretRef: testNamespace + "/" + defaultSecretName,
+		},
+		{
+			name:                  "issuer namespace",
+			givenSecretAccess:     []string{"tenant"},
+			whenResourceNamespace: "tenant",
+			whenConfig:            credentialsConfig{SecretRef: defaultSecretName},
+			thenLookups:           []secretLookup{{namespace: "tenant", name: defaultSecretName}},
+			thenSecretRef:         "tenant/" + defaultSecretName,
+		},
+		{
+			name:                  "webhook namespace without secret access to the issuer namespace",
+			givenSecretAccess:     []string{"other"},
+			whenResourceNamespace: "tenant",
+			whenConfig:            credentialsConfig{SecretRef: defaultSecretName},
+			thenLookups:           []secretLookup{{namespace: testNamespace, name: defaultSecretName}},
+			thenSecretRef:         testNamespace + "/" + defaultSecretName,
+		},
+		{
+			name:                  "webhook namespace for a cluster issuer without secret access",
+			whenResourceNamespace: "cert-manager",
+			whenConfig:            credentialsConfig{SecretRef: defaultSecretName},
+			thenLookups:           []secretLookup{{namespace: testNamespace, name: defaultSecretName}},
+			thenSecretRef:         testNamespace + "/" + defaultSecretName,
+		},
+		{
+			name:                  "issuer namespace falls back to webhook namespace if secret is not found",
+			givenSecretAccess:     []string{"tenant"},
+			whenResourceNamespace: "tenant",
+			whenConfig:            credentialsConfig{SecretRef: defaultSecretName},
+			thenLookups: []secretLookup{
+				{namespace: "tenant", name: defaultSecretName, err: errNotFound},
+				{namespace: testNamespace, name: defaultSecretName},
+			},
+			thenSecretRef: testNamespace + "/" + defaultSecretName,
+		},
+		{
+			name:                  "issuer namespace with secret access does not fall back if access is forbidden",
+			givenSecretAccess:     []string{"tenant"},
+			whenResourceNamespace: "tenant",
+			whenConfig:            credentialsConfig{SecretRef: defaultSecretName},
+			thenLookups:           []secretLookup{{namespace: "tenant", name: defaultSecretName, err: errForbidden}},
+			thenError: "failed to get secret cert-manager-webhook-ionos-cloud from namespace tenant: " +
+				errForbidden.Error(),
+		},
+		{
+			name:                  "issuer namespace does not fall back on other errors",
+			givenSecretAccess:     []string{"tenant"},
+			whenResourceNamespace: "tenant",
+			whenConfig:            credentialsConfig{SecretRef: defaultSecretName},
+			thenLookups:           []secretLookup{{namespace: "tenant", name: defaultSecretName, err: errK8Client}},
+			thenError:             "failed to get secret cert-manager-webhook-ionos-cloud from namespace tenant: k8 client error",
+		},
+		{
+			name:                  "explicit secretNamespace of the issuer",
+			whenResourceNamespace: "tenant",
+			whenConfig:            credentialsConfig{SecretRef: "creds", SecretNamespace: "tenant"},
+			thenLookups:           []secretLookup{{namespace: "tenant", name: "creds"}},
+			thenSecretRef:         "tenant/creds",
+		},
+		{
+			name:                  "explicit secretNamespace of a cluster issuer",
+			whenResourceNamespace: testNamespace,
+			whenConfig:            credentialsConfig{SecretRef: "creds", SecretNamespace: "other"},
+			thenLookups:           []secretLookup{{namespace: "other", name: "creds"}},
+			thenSecretRef:         "other/creds",
+		},
+		{
+			name:                          "explicit secretNamespace of a cluster issuer in a custom cluster resource namespace",
+			givenClusterResourceNamespace: "cluster-resources",
+			whenResourceNamespace:         "cluster-resources",
+			whenConfig:                    credentialsConfig{SecretRef: "creds", SecretNamespace: "other"},
+			thenLookups:                   []secretLookup{{namespace: "other", name: "creds"}},
+			thenSecretRef:                 "other/creds",
+		},
+		{
+			name:                  "explicit secretNamespace of another namespace is rejected for an issuer",
+			whenResourceNamespace: "tenant",
+			whenConfig:            credentialsConfig{SecretRef: "creds", SecretNamespace: "other"},
+			thenError: "secret namespace 'other' is not allowed for an Issuer in namespace 'tenant', only " +
+				"ClusterIssuers may refer to secrets in other namespaces",
+		},
+		{
+			name:                  "namespace/name secretRef of another namespace is rejected for an issuer",
+			whenResourceNamespace: "tenant",
+			whenConfig:            credentialsConfig{SecretRef: testNamespace + "/creds"},
+			thenError: "secret namespace '" + testNamespace + "' is not allowed for an Issuer in namespace " +
+				"'tenant', only ClusterIssuers may refer to secrets in other namespaces",
+		},
+		{
+			name:                  "explicit secretNamespace does not fall back",
+			whenResourceNamespace: testNamespace,
+			whenConfig:            credentialsConfig{SecretRef: "creds", SecretNamespace: "other"},
+			thenLookups:           []secretLookup{{namespace: "other", name: "creds", err: errNotFound}},
+			thenError:             `failed to get secret creds from namespace other: secrets "cert-manager-webhook-ionos-cloud" not found`,
+		},
+		{
+			name:                  "namespace/name secretRef",
+			whenResourceNamespace: testNamespace,
+			whenConfig:            credentialsConfig{SecretRef: "other/creds"},
+			thenLookups:           []secretLookup{{namespace: "other", name: "creds"}},
+			thenSecretRef:         "other/creds",
+		},
+		{
+			name:                  "namespace/name secretRef matching secretNamespace",
+			whenResourceNamespace: testNamespace,
+			whenConfig:            credentialsConfig{SecretRef: "other/creds", SecretNamespace: "other"},
+			thenLookups:           []secretLookup{{namespace: "other", name: "creds"}},
+			thenSecretRef:         "other/creds",
+		},
+		{
+			name:       "namespace/name secretRef conflicting with secretNamespace",
+			whenConfig: credentialsConfig{SecretRef: "other/creds", SecretNamespace: "tenant"},
+			thenError:  "secretRef 'other/creds' and secretNamespace 'tenant' refer to different namespaces",
+		},
+		{
+			name:       "invalid secretRef",
+			whenConfig: credentialsConfig{SecretRef: "/creds"},
+			thenError:  "invalid secretRef '/creds': expected name or namespace/name",
+		},
+	}
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			s.setupMocks()
+			if len(tc.thenLookups) > 0 {
+				coreV1Interface := mocks.NewCoreV1Interface(s.T())
+				for _, lookup := range tc.thenLookups {
+					secretsInterface := mocks.NewSecretInterface(s.T())
+					secretsInterface.EXPECT().Get(mock.Anything, lookup.name, v1.GetOptions{}).
+						Return(&corev1.Secret{}, lookup.err)
+					coreV1Interface.EXPECT().Secrets(lookup.namespace).Return(secretsInterface)
+				}
+				s.k8Client.EXPECT().CoreV1().Return(coreV1Interface)
+			}
+
+			resolver := NewResolver(testNamespace, createTestK8Factory(s.k8Client), createTestDNSFactory(s.dnsAPIMock),
+				createTestAuthAPIFactory(s.authAPIMock), s.logger,
+				WithClusterResourceNamespace(tc.givenClusterResourceNamespace),
+				WithSecretAccess(false, tc.givenSecretAccess)).(*ionosCloudDnsProviderResolver)
+			require.NoError(s.T(), resolver.Initialize(&rest.Config{}, nil))
+			_, secretRef, err := resolver.getCredentialsSecret(context.Background(),
+				&v1alpha1.ChallengeRequest{ResourceNamespace: tc.whenResourceNamespace}, tc.whenConfig)
+			if tc.thenError != "" {
+				require.EqualError(s.T(), err, tc.thenError)
+			} else {
+				require.NoError(s.T(), err)
+				require.Equal(s.T(), tc.thenSecretRef, secretRef)
+			}
+		})
+	}
+}
+
+func (s *ResolverTestSuite) TestAmbientCredentials() {
+	tokenFile := filepath.Join(s.T().TempDir(), "token")
+	require.NoError(s.T(), os.WriteFile(tokenFile, []byte("file-token\n"), 0o600))
+	testCases := []struct {
+		name               string
+		givenEnv           map[string]string
+		whenAllowAmbient   bool
+		whenConfig         string
+		whenSecretContent  map[string][]byte
+		whenK8ClientError  error
+		whenTokenGenerated bool
+		thenToken          string
+		thenError          string
+	}{
+		{
+			name:             "token from environment",
+			givenEnv:         map[string]string{"IONOS_TOKEN": "env-token"},
+			whenAllowAmbient: true,
+			thenToken:        "env-token",
+		},
+		{
+			name:               "username and password from environment",
+			givenEnv:           map[string]string{"IONOS_USERNAME": "username", "IONOS_PASSWORD": "password"},
+			whenAllowAmbient:   true,
+			whenTokenGenerated: true,
+			thenToken:          "token",
+		},
+		{
+			name:             "token from file",
+			givenEnv:         map[string]string{"IONOS_TOKEN_FILE": tokenFile},
+			whenAllowAmbient: true,
+			thenToken:        "file-token",
+		},
+		{
+			name:             "unreadable file",
+			givenEnv:         map[string]string{"IONOS_TOKEN_FILE": tokenFile + ".missing"},
+			whenAllowAmbient: true,
+			thenError:        "failed to read ambient credentials from " + tokenFile + ".missing: open " + tokenFile + ".missing: no such file or directory",
+		},
+		{
+			name:              "ambient credentials not allowed",
+			givenEnv:          map[string]string{"IONOS_TOKEN": "env-token"},
+			whenSecretContent: secretDataWithToken,
+			thenToken:         "token",
+		},
+		{
+			name:              "ambient credentials not allowed and no secret",
+			givenEnv:          map[string]string{"IONOS_TOKEN": "env-token"},
+			whenK8ClientError: errK8Client,
+			thenError:         "failed to get secret cert-manager-webhook-ionos-cloud from namespace unit-test: k8 client error (ambient credentials are not allowed for this issuer)",
+		},
+		{
+			name:              "no ambient credentials",
+			whenAllowAmbient:  true,
+			whenSecretContent: secretDataWithToken,
+			thenToken:         "token",
+		},
+		{
+			name:              "explicit secretRef",
+			givenEnv:          map[string]string{"IONOS_TOKEN": "env-token"},
+			whenAllowAmbient:  true,
+			whenConfig:        `{"secretRef":"cert-manager-webhook-ionos-cloud"}`,
+			whenSecretContent: secretDataWithToken,
+			thenToken:         "token",
+		},
+	}
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			s.setupMocks()
+			if tc.whenSecretContent != nil || tc.whenK8ClientError != nil {
+				setUpK8ClientExpectations(s.T(), s.k8Client, tc.whenK8ClientError, tc.whenSecretContent)
+			}
+			if tc.whenTokenGenerated {
+				s.authAPIMock.EXPECT().GenerateToken(mock.Anything, int32(3600)).Return("token", nil)
+			}
+			var usedToken string
+			dnsAPIFactory := func(token string, _ APIConfig) clouddns.DNSAPI {
+				usedToken = token
+				return s.dnsAPIMock
+			}
+
+			resolver := NewResolver(testNamespace, createTestK8Factory(s.k8Client), dnsAPIFactory,
+				createTestAuthAPIFactory(s.authAPIMock), s.logger).(*ionosCloudDnsProviderResolver)
+			resolver.getenv = func(key string) string { return tc.givenEnv[key] }
+			require.NoError(s.T(), resolver.Initialize(&rest.Config{}, nil))
+			challenge := &v1alpha1.ChallengeRequest{AllowAmbientCredentials: tc.whenAllowAmbient}
+			if tc.whenConfig != "" {
+				challenge.Config = &apiextensionsv1.JSON{Raw: []byte(tc.whenConfig)}
+			}
+			config, err := loadSolverConfig(challenge)
+			require.NoError(s.T(), err)
+			_, _, err = resolver.newDNSAPI(context.Background(), challenge, config)
+			if tc.thenError != "" {
+				require.EqualError(s.T(), err, tc.thenError)
+			} else {
+				require.NoError(s.T(), err)
+				require.Equal(s.T(), tc.thenToken, usedToken)
+			}
+		})
+	}
+}
+
+func (s *ResolverTestSuite) TestStaticTokenExpiry() {
+	now := time.Unix(1_700_000_000, 0).UTC()
+	secretRef := testNamespace + "/" + defaultSecretName
+	testCases := []struct {
+		name          string
+		givenExpiry   time.Time
+		thenDays      float64
+		thenWarning   bool
+		thenErrorText string
+	}{
+		{
+			name:        "valid token",
+			givenExpiry: now.Add(30 * 24 * time.Hour),
+			thenDays:    30,
+		},
+		{
+			name:        "token expiring soon",
+			givenExpiry: now.Add(3 * 24 * time.Hour),
+			thenDays:    3,
+			thenWarning: true,
+		},
+		{
+			name:          "expired token",
+			givenExpiry:   now.Add(-12 * time.Hour),
+			thenDays:      -0.5,
+			thenErrorText: "auth token from " + secretRef + " expired at 2023-11-14T10:13:20Z",
+		},
+	}
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			s.setupMocks()
+			setUpK8ClientExpectations(s.T(), s.k8Client, nil,
+				map[string][]byte{defaultAuthTokenSecretKey: []byte(testJWT(tc.givenExpiry))})
+			core, logs := observer.New(zap.WarnLevel)
+			resolver := NewResolver(testNamespace, createTestK8Factory(s.k8Client), createTestDNSFactory(s.dnsAPIMock),
+				createTestAuthAPIFactory(s.authAPIMock), zap.New(core)).(*ionosCloudDnsProviderResolver)
+			resolver.tokenCache.now = func() time.Time { return now }
+			require.NoError(s.T(), resolver.Initialize(&rest.Config{}, nil))
+
+			config, err := loadSolverConfig(&v1alpha1.ChallengeRequest{})
+			require.NoError(s.T(), err)
+			_, _, err = resolver.newDNSAPI(context.Background(), &v1alpha1.ChallengeRequest{}, config)
+			if tc.thenErrorText != "" {
+				require.ErrorContains(s.T(), err, tc.thenErrorText)
+			} else {
+				require.NoError(s.T(), err)
+			}
+			days, err := testutil.GetGaugeMetricValue(metrics.AuthTokenDaysUntilExpiry.WithLabelValues(secretRef))
+			require.NoError(s.T(), err)
+			require.Equal(s.T(), tc.thenDays, days)
+			require.Equal(s.T(), tc.thenWarning, logs.FilterMessageSnippet("auth token expires soon").Len() == 1)
+		})
+	}
+}
+
 func createTestDNSFactory(dnsAPIMock *mocks.DNSAPI) DNSAPIFactory {
-	return func(_ string) clouddns.DNSAPI {
+	return func(_ string, _ APIConfig) clouddns.DNSAPI {
 		return dnsAPIMock
 	}
 }
@@ -661,9 +1318,9 @@ func createTestK8Factory(k8Client *mocks.K8Client) K8ClientFactory {
 	}
 }
 
-func createTestGenerateTokenFunc(err error) GenerateTokenFunc {
-	return func(cfg *ionoscloud_auth.Configuration) (string, error) {
-		return "token", err
+func createTestAuthAPIFactory(authAPIMock *mocks.AuthAPI) AuthAPIFactory {
+	return func(_, _ string, _ APIConfig) cloudauth.AuthAPI {
+		return authAPIMock
 	}
 }
 
@@ -672,7 +1329,12 @@ func setUpK8ClientExpectations(t *testing.T, k8Client *mocks.K8Client, err error
 	coreV1Interface := mocks.NewCoreV1Interface(t)
 	k8Secret := &corev1.Secret{}
 	k8Secret.Data = data
-	secretsInterface.EXPECT().Get(context.Background(), defaultSecretName, v1.GetOptions{}).
+	// the secret is read within the bounded context of the challenge
+	hasDeadline := mock.MatchedBy(func(ctx context.Context) bool {
+		_, ok := ctx.Deadline()
+		return ok
+	})
+	secretsInterface.EXPECT().Get(hasDeadline, defaultSecretName, v1.GetOptions{}).
 		Return(k8Secret, err)
 
 	coreV1Interface.EXPECT().Secrets(testNamespace).Return(secretsInterface)
@@ -0,0 +1,93 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// getCredentialsSecret fetches the secret referenced by the solver config, within the context of the challenge. It
+// returns the secret together with its namespace/name reference.
+//
+// The namespace of the secret is determined in the following order:
+//  1. the namespace given in secretRef using the namespace/name form
+//  2. the namespace given in secretNamespace
+//  3. the namespace of the Issuer (ch.ResourceNamespace) if the webhook may read secrets there, falling back to the
+//     namespace of the webhook if the secret does not exist there
+//  4. the namespace of the webhook
+//
+// A namespaced Issuer may only name its own namespace explicitly. Issuers omitting the namespace still use the
+// secret in the namespace of the webhook, as before the secret could be kept in the namespace of the Issuer.
+func (s *ionosCloudDnsProviderResolver) getCredentialsSecret(ctx context.Context, ch *v1alpha1.ChallengeRequest,
+	config credentialsConfig,
+) (*corev1.Secret, string, error) {
+	name, namespaces, err := s.secretLocation(ch, config)
+	if err != nil {
+		return nil, "", err
+	}
+	for i, namespace := range namespaces {
+		if s.secretInformer != nil {
+			if secret, ok := s.secretInformer.get(namespace, name); ok {
+				return secret, namespace + "/" + name, nil
+			}
+		}
+		callCtx, cancel := context.WithTimeout(ctx, apiCallTimeout)
+		secret, err := s.k8Client.CoreV1().Secrets(namespace).Get(callCtx, name, v1.GetOptions{})
+		cancel()
+		if err == nil {
+			return secret, namespace + "/" + name, nil
+		}
+		if i < len(namespaces)-1 && k8serrors.IsNotFound(err) {
+			s.logger.Debug("secret not found, trying next namespace", zap.String("secretRef", name),
+				zap.String("namespace", namespace), zap.Error(err))
+			continue
+		}
+		return nil, "", fmt.Errorf("failed to get secret %s from namespace %s: %w", name, namespace, err)
+	}
+	return nil, "", fmt.Errorf("no namespace to look up secret %s", name)
+}
+
+// secretLocation returns the name of the credentials secret and the namespaces to look it up in, by precedence.
+func (s *ionosCloudDnsProviderResolver) secretLocation(ch *v1alpha1.ChallengeRequest,
+	config credentialsConfig,
+) (string, []string, error) {
+	name := config.SecretRef
+	namespace := config.SecretNamespace
+	if refNamespace, refName, found := strings.Cut(config.SecretRef, "/"); found {
+		if refNamespace == "" || refName == "" {
+			return "", nil, fmt.Errorf("invalid secretRef '%s': expected name or namespace/name", config.SecretRef)
+		}
+		if namespace != "" && namespace != refNamespace {
+			return "", nil, fmt.Errorf("secretRef '%s' and secretNamespace '%s' refer to different namespaces",
+				config.SecretRef, config.SecretNamespace)
+		}
+		name, namespace = refName, refNamespace
+	}
+	if namespace != "" {
+		clusterResourceNamespace := s.clusterResourceNamespace
+		if clusterResourceNamespace == "" {
+			clusterResourceNamespace = s.namespace
+		}
+		if namespace != ch.ResourceNamespace && ch.ResourceNamespace != clusterResourceNamespace {
+			return "", nil, fmt.Errorf("secret namespace '%s' is not allowed for an Issuer in namespace '%s', only "+
+				"ClusterIssuers may refer to secrets in other namespaces", namespace, ch.ResourceNamespace)
+		}
+		return name, []string{namespace}, nil
+	}
+	if ch.ResourceNamespace != "" && ch.ResourceNamespace != s.namespace && s.secretAccessAllowed(ch.ResourceNamespace) {
+		return name, []string{ch.ResourceNamespace, s.namespace}, nil
+	}
+	return name, []string{s.namespace}, nil
+}
+
+// secretAccessAllowed reports whether the webhook may read secrets in the namespace.
+func (s *ionosCloudDnsProviderResolver) secretAccessAllowed(namespace string) bool {
+	return s.secretAccessClusterWide || slices.Contains(s.secretAccessNamespaces, namespace)
+}
@@ -0,0 +1,85 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	listerscorev1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// secretInformer serves the credentials secrets from the cache of a shared informer, instead of fetching them
+// from the API server for every challenge. Secrets outside of the watched namespace, or not matching the label
+// selector, are not served by the informer.
+type secretInformer struct {
+	namespace string
+	informer  cache.SharedIndexInformer
+	lister    listerscorev1.SecretLister
+}
+
+// newSecretInformer creates an informer for the secrets in the given namespace, or in all namespaces if the
+// namespace is empty. onChange is called with the namespace/name key of every updated or deleted secret.
+func newSecretInformer(k8Client K8Client, namespace, labelSelector string, onChange func(key string),
+) (*secretInformer, error) {
+	if _, err := labels.Parse(labelSelector); err != nil {
+		return nil, fmt.Errorf("invalid secret label selector '%s': %w", labelSelector, err)
+	}
+	lw := &cache.ListWatch{
+		ListWithContextFunc: func(ctx context.Context, options v1.ListOptions) (runtime.Object, error) {
+			options.LabelSelector = labelSelector
+			return k8Client.CoreV1().Secrets(namespace).List(ctx, options)
+		},
+		WatchFuncWithContext: func(ctx context.Context, options v1.ListOptions) (watch.Interface, error) {
+			options.LabelSelector = labelSelector
+			return k8Client.CoreV1().Secrets(namespace).Watch(ctx, options)
+		},
+	}
+	informer := cache.NewSharedIndexInformer(cache.ToListWatcherWithWatchListSemantics(lw, k8Client),
+		&corev1.Secret{}, 0, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj any) {
+			oldSecret, newSecret := oldObj.(*corev1.Secret), newObj.(*corev1.Secret)
+			if oldSecret.ResourceVersion != newSecret.ResourceVersion {
+				onChange(oldSecret.Namespace + "/" + oldSecret.Name)
+			}
+		},
+		DeleteFunc: func(obj any) {
+			if key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj); err == nil {
+				onChange(key)
+			}
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to register secret event handler: %w", err)
+	}
+	return &secretInformer{
+		namespace: namespace,
+		informer:  informer,
+		lister:    listerscorev1.NewSecretLister(informer.GetIndexer()),
+	}, nil
+}
+
+func (i *secretInformer) run(stopCh <-chan struct{}) {
+	go i.informer.Run(stopCh)
+}
+
+// get returns the secret from the informer cache. It returns false if the secret is not served by the informer,
+// or if the informer has not synced yet.
+func (i *secretInformer) get(namespace, name string) (*corev1.Secret, bool) {
+	if i.namespace != v1.NamespaceAll && i.namespace != namespace {
+		return nil, false
+	}
+	if !i.informer.HasSynced() {
+		return nil, false
+	}
+	secret, err := i.lister.Secrets(namespace).Get(name)
+	if err != nil {
+		return nil, false
+	}
+	return secret, true
+}
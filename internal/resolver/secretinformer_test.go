@@ -0,0 +1,125 @@
+//go:build unit
+
+package resolver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ionos-cloud/cert-manager-webhook-ionos-cloud/internal/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+)
+
+func TestSecretInformer(t *testing.T) {
+	client := fake.NewClientset(
+		testSecret("creds", "1", map[string]string{"app": "ionos"}),
+		testSecret("unlabeled", "1", nil),
+	)
+	changes := make(chan string, 10)
+	informer, err := newSecretInformer(client, testNamespace, "app=ionos", func(key string) { changes <- key })
+	require.NoError(t, err)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	informer.run(stopCh)
+	require.True(t, cache.WaitForCacheSync(stopCh, informer.informer.HasSynced))
+
+	secret, ok := informer.get(testNamespace, "creds")
+	require.True(t, ok)
+	require.Equal(t, "1", secret.ResourceVersion)
+	_, ok = informer.get(testNamespace, "unlabeled")
+	require.False(t, ok, "secrets not matching the label selector are not served")
+	_, ok = informer.get("tenant", "creds")
+	require.False(t, ok, "secrets outside of the watched namespace are not served")
+
+	_, err = client.CoreV1().Secrets(testNamespace).Update(context.Background(),
+		testSecret("creds", "2", map[string]string{"app": "ionos"}), v1.UpdateOptions{})
+	require.NoError(t, err)
+	requireSecretChange(t, changes, testNamespace+"/creds")
+
+	require.NoError(t, client.CoreV1().Secrets(testNamespace).Delete(context.Background(), "creds", v1.DeleteOptions{}))
+	requireSecretChange(t, changes, testNamespace+"/creds")
+}
+
+func TestSecretInformerInvalidLabelSelector(t *testing.T) {
+	_, err := newSecretInformer(fake.NewClientset(), testNamespace, "app in", func(string) {})
+	require.ErrorContains(t, err, "invalid secret label selector 'app in'")
+}
+
+func TestSecretInformerAllNamespacesRequiresLabelSelector(t *testing.T) {
+	resolver := NewResolver(testNamespace, func(_ *rest.Config) (K8Client, error) { return fake.NewClientset(), nil },
+		createTestDNSFactory(mocks.NewDNSAPI(t)), createTestAuthAPIFactory(mocks.NewAuthAPI(t)), zap.NewNop(),
+		WithSecretInformer(true, ""))
+	err := resolver.Initialize(&rest.Config{}, nil)
+	require.EqualError(t, err, "a secret label selector is required to watch the secrets of all namespaces")
+}
+
+func TestResolverWithSecretInformer(t *testing.T) {
+	client := fake.NewClientset(testSecret(defaultSecretName, "1", nil))
+	authAPIMock := mocks.NewAuthAPI(t)
+	resolver := NewResolver(testNamespace, func(_ *rest.Config) (K8Client, error) { return client, nil },
+		createTestDNSFactory(mocks.NewDNSAPI(t)), createTestAuthAPIFactory(authAPIMock), zap.NewNop(),
+		WithSecretInformer(false, "")).(*ionosCloudDnsProviderResolver)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	require.NoError(t, resolver.Initialize(&rest.Config{}, stopCh))
+	require.True(t, cache.WaitForCacheSync(stopCh, resolver.secretInformer.informer.HasSynced))
+	client.ClearActions()
+
+	_, secretRef, err := resolver.getCredentialsSecret(context.Background(), &v1alpha1.ChallengeRequest{},
+		credentialsConfig{SecretRef: defaultSecretName})
+	require.NoError(t, err)
+	require.Equal(t, testNamespace+"/"+defaultSecretName, secretRef)
+	require.Empty(t, client.Actions(), "secret should be served from the informer cache")
+
+	resolver.tokenCache.entries[secretRef] = cachedToken{
+		issuedToken: issuedToken{
+			token:     testJWTWithID("token-id", time.Now().Add(time.Hour)),
+			expiresAt: time.Now().Add(time.Hour),
+			authAPI:   authAPIMock,
+		},
+		version: "1",
+	}
+	_, err = client.CoreV1().Secrets(testNamespace).Update(context.Background(),
+		testSecret(defaultSecretName, "2", nil), v1.UpdateOptions{})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		_, ok := resolver.tokenCache.lookup(secretRef, "1")
+		return !ok
+	}, 5*time.Second, 10*time.Millisecond, "cached token should be invalidated when the secret changes")
+	authAPIMock.AssertNotCalled(t, "DeleteToken", mock.Anything, "token-id")
+
+	authAPIMock.EXPECT().DeleteToken(mock.Anything, "token-id").Return(nil).Once()
+	resolver.tokenCache.revokeAll()
+}
+
+func testSecret(name, resourceVersion string, labels map[string]string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: v1.ObjectMeta{
+			Name:            name,
+			Namespace:       testNamespace,
+			ResourceVersion: resourceVersion,
+			Labels:          labels,
+		},
+		Data: secretDataWithToken,
+	}
+}
+
+func requireSecretChange(t *testing.T, changes <-chan string, key string) {
+	t.Helper()
+	select {
+	case changed := <-changes:
+		require.Equal(t, key, changed)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no change reported for secret %s", key)
+	}
+}
@@ -0,0 +1,296 @@
+package resolver
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ionos-cloud/cert-manager-webhook-ionos-cloud/internal/cloudauth"
+	ionoscloud_auth "github.com/ionos-cloud/sdk-go-auth"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// generatedTokenTTL is the lifetime requested for tokens generated from a username and password.
+	generatedTokenTTL = time.Hour
+	// tokenRefreshMargin is how long before its expiry a cached token is replaced by a new one.
+	tokenRefreshMargin = 10 * time.Minute
+	// tokenSweepInterval is the minimum time between two sweeps of stale tokens for the same credentials.
+	tokenSweepInterval = time.Hour
+	// tokenRevokeTimeout bounds the deletion of the cached tokens when the webhook shuts down.
+	tokenRevokeTimeout = 30 * time.Second
+)
+
+// tokenCache keeps the tokens issued for credentials sources, e.g. the tokens generated from username/password
+// credentials, so that they can be reused across challenges instead of issuing a new token for every Present and
+// CleanUp call. Entries are keyed by the identity of the credentials source (e.g. the secret) and are only
+// returned while the version of the source matches and the token is not about to expire.
+// Tokens generated by the webhook which are replaced are retired rather than deleted, as challenges in flight may
+// still use them. Retired tokens are dropped once they expire, and the tokens still valid on shutdown are deleted
+// through the Auth API.
+type tokenCache struct {
+	mu         sync.Mutex
+	entries    map[string]cachedToken
+	retired    []cachedToken
+	generated  map[string]map[string]time.Time
+	lastSweeps map[string]time.Time
+	group      singleflight.Group
+	sweep      bool
+	now        func() time.Time
+	logger     *zap.Logger
+}
+
+type cachedToken struct {
+	issuedToken
+	version string
+}
+
+// issuedToken is a token together with its expiry. authAPI is set for the tokens generated by the webhook, which
+// are deleted once they are no longer needed.
+type issuedToken struct {
+	token     string
+	expiresAt time.Time
+	authAPI   cloudauth.AuthAPI
+}
+
+func newTokenCache(logger *zap.Logger) *tokenCache {
+	return &tokenCache{
+		entries:    make(map[string]cachedToken),
+		generated:  make(map[string]map[string]time.Time),
+		lastSweeps: make(map[string]time.Time),
+		now:        time.Now,
+		logger:     logger,
+	}
+}
+
+// get returns the cached token for the given key and version, or calls issue to obtain a new one. Tokens without
+// a known expiry are not cached. Concurrent calls for the same key and version share a single call to issue.
+func (c *tokenCache) get(key, version string, issue func() (issuedToken, error)) (string, error) {
+	if token, ok := c.lookup(key, version); ok {
+		return token, nil
+	}
+	token, err, _ := c.group.Do(key+"@"+version, func() (any, error) {
+		if token, ok := c.lookup(key, version); ok {
+			return token, nil
+		}
+		issued, err := issue()
+		if err != nil {
+			return "", err
+		}
+		if issued.expiresAt.IsZero() {
+			return issued.token, nil
+		}
+		c.mu.Lock()
+		if replaced, ok := c.entries[key]; ok {
+			c.retireLocked(replaced)
+		}
+		c.entries[key] = cachedToken{issuedToken: issued, version: version}
+		c.recordLocked(key, issued)
+		sweep := c.sweep && issued.authAPI != nil && c.now().Sub(c.lastSweeps[key]) >= tokenSweepInterval
+		if sweep {
+			c.lastSweeps[key] = c.now()
+		}
+		c.mu.Unlock()
+		if sweep {
+			go c.sweepStaleTokens(key, issued.authAPI)
+		}
+		return issued.token, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return token.(string), nil
+}
+
+// generate returns a function generating a token valid for generatedTokenTTL with the given client. The call to the
+// Auth API is bounded by apiCallTimeout and cancelled with the given context.
+func (c *tokenCache) generate(ctx context.Context, key string, authAPI cloudauth.AuthAPI) func() (issuedToken, error) {
+	return func() (issuedToken, error) {
+		c.logger.Info("token not provided, attempting to authenticate using username and password",
+			zap.String("credentials", key))
+		callCtx, cancel := context.WithTimeout(ctx, apiCallTimeout)
+		token, err := authAPI.GenerateToken(callCtx, int32(generatedTokenTTL.Seconds()))
+		cancel()
+		if err != nil {
+			return issuedToken{}, err
+		}
+		expiresAt, ok := tokenExpiry(token)
+		if !ok {
+			expiresAt = c.now().Add(generatedTokenTTL)
+		}
+		return issuedToken{token: token, expiresAt: expiresAt, authAPI: authAPI}, nil
+	}
+}
+
+func (c *tokenCache) lookup(key, version string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || entry.version != version {
+		return "", false
+	}
+	if c.now().Add(tokenRefreshMargin).After(entry.expiresAt) {
+		return "", false
+	}
+	return entry.token, true
+}
+
+// invalidate removes the cached token of the given credentials source if it is the given token, which was rejected
+// by the API. A token cached in the meantime, e.g. after another call was rejected, is kept. The rejected token is
+// not deleted, as the Auth API would reject it as well.
+func (c *tokenCache) invalidate(key, token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.entries[key]; ok && entry.token == token {
+		delete(c.entries, key)
+	}
+}
+
+// retire removes the cached token of the given credentials source, e.g. when its credentials changed. The token is
+// kept until it expires or the webhook shuts down, as challenges in flight may still use it.
+func (c *tokenCache) retire(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.entries[key]; ok {
+		delete(c.entries, key)
+		c.retireLocked(entry)
+	}
+}
+
+// retireLocked keeps a token generated by the webhook until it expires and drops the retired tokens which have
+// expired. c.mu must be held.
+func (c *tokenCache) retireLocked(entry cachedToken) {
+	now := c.now()
+	retired := c.retired[:0]
+	for _, r := range c.retired {
+		if now.Before(r.expiresAt) {
+			retired = append(retired, r)
+		}
+	}
+	if entry.authAPI != nil && now.Before(entry.expiresAt) {
+		retired = append(retired, entry)
+	}
+	c.retired = retired
+}
+
+// recordLocked remembers the id of a token generated by the webhook, so that it can be swept once it expired. The
+// ids are only needed for sweeping, so nothing is recorded if sweeping is disabled. c.mu must be held.
+func (c *tokenCache) recordLocked(key string, issued issuedToken) {
+	if !c.sweep || issued.authAPI == nil {
+		return
+	}
+	tokenId, ok := tokenID(issued.token)
+	if !ok {
+		return
+	}
+	if c.generated[key] == nil {
+		c.generated[key] = make(map[string]time.Time)
+	}
+	c.generated[key][tokenId] = issued.expiresAt
+}
+
+// forget drops the ids of tokens which were deleted or no longer exist.
+func (c *tokenCache) forget(key string, tokenIds ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, tokenId := range tokenIds {
+		delete(c.generated[key], tokenId)
+	}
+	if len(c.generated[key]) == 0 {
+		delete(c.generated, key)
+	}
+}
+
+// revokeAll deletes all cached and retired tokens. It is called when the webhook shuts down, after the contexts of
+// the challenges are cancelled, so the deletions are bounded by their own timeout.
+func (c *tokenCache) revokeAll() {
+	c.mu.Lock()
+	entries := c.retired
+	for _, entry := range c.entries {
+		entries = append(entries, entry)
+	}
+	c.entries = make(map[string]cachedToken)
+	c.retired = nil
+	c.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), tokenRevokeTimeout)
+	defer cancel()
+	for _, entry := range entries {
+		c.revoke(ctx, entry)
+	}
+}
+
+// revoke deletes the token if it was generated by the webhook and has not expired yet.
+func (c *tokenCache) revoke(ctx context.Context, entry cachedToken) {
+	if entry.authAPI == nil || !c.now().Before(entry.expiresAt) {
+		return
+	}
+	tokenId, ok := tokenID(entry.token)
+	if !ok {
+		c.logger.Debug("token has no id, it can not be deleted")
+		return
+	}
+	callCtx, cancel := context.WithTimeout(ctx, apiCallTimeout)
+	defer cancel()
+	if err := entry.authAPI.DeleteToken(callCtx, tokenId); err != nil {
+		c.logger.Warn("failed to delete token", zap.String("tokenId", tokenId), zap.Error(err))
+		return
+	}
+	c.logger.Info("token deleted", zap.String("tokenId", tokenId))
+}
+
+// sweepStaleTokens deletes the expired tokens which the webhook generated from the given credentials, e.g. the
+// replaced tokens. Only the ids recorded when the tokens were generated are considered, so tokens created by other
+// tools are never deleted. Ids of tokens which no longer exist are dropped.
+func (c *tokenCache) sweepStaleTokens(key string, authAPI cloudauth.AuthAPI) {
+	c.mu.Lock()
+	expired := make(map[string]bool, len(c.generated[key]))
+	for tokenId, expiresAt := range c.generated[key] {
+		expired[tokenId] = !c.now().Before(expiresAt)
+	}
+	c.mu.Unlock()
+	if len(expired) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), apiCallTimeout)
+	tokens, err := authAPI.GetTokens(ctx)
+	cancel()
+	if err != nil {
+		c.logger.Warn("failed to list tokens for sweeping", zap.String("credentials", key), zap.Error(err))
+		return
+	}
+	var gone []string
+	for tokenId, isExpired := range expired {
+		exists := slices.ContainsFunc(tokens, func(token ionoscloud_auth.Token) bool {
+			return token.Id != nil && *token.Id == tokenId
+		})
+		if !exists {
+			gone = append(gone, tokenId)
+			continue
+		}
+		if !isExpired {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), apiCallTimeout)
+		err = authAPI.DeleteToken(ctx, tokenId)
+		cancel()
+		if err != nil {
+			c.logger.Warn("failed to delete stale token", zap.String("tokenId", tokenId), zap.Error(err))
+			continue
+		}
+		gone = append(gone, tokenId)
+	}
+	c.forget(key, gone...)
+	c.logger.Info("stale tokens swept", zap.String("credentials", key), zap.Int("removed", len(gone)))
+}
+
+// credentialsFingerprint returns a digest of the username and password, so that a cached token is not
+// reused when the credentials change without the version of their source changing.
+func credentialsFingerprint(username, password string) string {
+	sum := sha256.Sum256([]byte(username + "\x00" + password))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,218 @@
+//go:build unit
+
+package resolver
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ionos-cloud/cert-manager-webhook-ionos-cloud/internal/mocks"
+	ionoscloud_auth "github.com/ionos-cloud/sdk-go-auth"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestTokenCache(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	testCases := []struct {
+		name             string
+		givenToken       string
+		whenVersion      string
+		whenElapsed      time.Duration
+		thenGenerateRuns int
+	}{
+		{
+			name:             "same version reuses token",
+			givenToken:       "token",
+			whenVersion:      "1",
+			whenElapsed:      time.Minute,
+			thenGenerateRuns: 1,
+		},
+		{
+			name:             "changed version generates new token",
+			givenToken:       "token",
+			whenVersion:      "2",
+			whenElapsed:      time.Minute,
+			thenGenerateRuns: 2,
+		},
+		{
+			name:             "token without expiry is refreshed ahead of the requested ttl",
+			givenToken:       "token",
+			whenVersion:      "1",
+			whenElapsed:      generatedTokenTTL - tokenRefreshMargin + time.Second,
+			thenGenerateRuns: 2,
+		},
+		{
+			name:             "jwt expiry is respected",
+			givenToken:       testJWT(now.Add(15 * time.Minute)),
+			whenVersion:      "1",
+			whenElapsed:      6 * time.Minute,
+			thenGenerateRuns: 2,
+		},
+		{
+			name:             "jwt still valid",
+			givenToken:       testJWT(now.Add(15 * time.Minute)),
+			whenVersion:      "1",
+			whenElapsed:      4 * time.Minute,
+			thenGenerateRuns: 1,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			authAPIMock := mocks.NewAuthAPI(t)
+			authAPIMock.EXPECT().GenerateToken(mock.Anything, int32(3600)).Return(tc.givenToken, nil).Times(tc.thenGenerateRuns)
+			cache := newTokenCache(zap.NewNop())
+			cache.now = func() time.Time { return now }
+
+			token, err := cache.get("ns/secret", "1", cache.generate(context.Background(), "ns/secret", authAPIMock))
+			require.NoError(t, err)
+			require.Equal(t, tc.givenToken, token)
+
+			cache.now = func() time.Time { return now.Add(tc.whenElapsed) }
+			token, err = cache.get("ns/secret", tc.whenVersion, cache.generate(context.Background(), "ns/secret", authAPIMock))
+			require.NoError(t, err)
+			require.Equal(t, tc.givenToken, token)
+		})
+	}
+}
+
+func TestTokenCacheGenerateError(t *testing.T) {
+	authAPIMock := mocks.NewAuthAPI(t)
+	authAPIMock.EXPECT().GenerateToken(mock.Anything, int32(3600)).Return("", errTokenGeneration).Once()
+	authAPIMock.EXPECT().GenerateToken(mock.Anything, int32(3600)).Return("token", nil).Once()
+	cache := newTokenCache(zap.NewNop())
+
+	_, err := cache.get("ns/secret", "1", cache.generate(context.Background(), "ns/secret", authAPIMock))
+	require.True(t, errors.Is(err, errTokenGeneration))
+
+	token, err := cache.get("ns/secret", "1", cache.generate(context.Background(), "ns/secret", authAPIMock))
+	require.NoError(t, err)
+	require.Equal(t, "token", token)
+}
+
+func TestTokenCacheRetiresReplacedToken(t *testing.T) {
+	now := time.Now()
+	authAPIMock := mocks.NewAuthAPI(t)
+	authAPIMock.EXPECT().GenerateToken(mock.Anything, int32(3600)).Return(testJWTWithID("first", now.Add(time.Hour)), nil).Once()
+	authAPIMock.EXPECT().GenerateToken(mock.Anything, int32(3600)).Return(testJWTWithID("second", now.Add(time.Hour)), nil).Once()
+	cache := newTokenCache(zap.NewNop())
+
+	_, err := cache.get("ns/secret", "1", cache.generate(context.Background(), "ns/secret", authAPIMock))
+	require.NoError(t, err)
+	_, err = cache.get("ns/secret", "2", cache.generate(context.Background(), "ns/secret", authAPIMock))
+	require.NoError(t, err)
+	authAPIMock.AssertNotCalled(t, "DeleteToken", mock.Anything, "first")
+
+	authAPIMock.EXPECT().DeleteToken(mock.Anything, "first").Return(nil).Once()
+	authAPIMock.EXPECT().DeleteToken(mock.Anything, "second").Return(nil).Once()
+	cache.revokeAll()
+}
+
+func TestTokenCacheDropsExpiredRetiredTokens(t *testing.T) {
+	now := time.Now()
+	authAPIMock := mocks.NewAuthAPI(t)
+	authAPIMock.EXPECT().GenerateToken(mock.Anything, int32(3600)).Return(testJWTWithID("first", now.Add(time.Hour)), nil).Once()
+	authAPIMock.EXPECT().GenerateToken(mock.Anything, int32(3600)).Return(testJWTWithID("second", now.Add(3*time.Hour)), nil).Once()
+	authAPIMock.EXPECT().DeleteToken(mock.Anything, "second").Return(nil).Once()
+	cache := newTokenCache(zap.NewNop())
+
+	_, err := cache.get("ns/secret", "1", cache.generate(context.Background(), "ns/secret", authAPIMock))
+	require.NoError(t, err)
+	cache.now = func() time.Time { return now.Add(2 * time.Hour) }
+	cache.retire("ns/secret")
+	_, err = cache.get("ns/secret", "1", cache.generate(context.Background(), "ns/secret", authAPIMock))
+	require.NoError(t, err)
+	require.Empty(t, cache.retired)
+	cache.revokeAll()
+}
+
+func TestTokenCacheRecordsGeneratedTokensOnlyForSweeping(t *testing.T) {
+	now := time.Now()
+	authAPIMock := mocks.NewAuthAPI(t)
+	authAPIMock.EXPECT().GenerateToken(mock.Anything, int32(3600)).Return(testJWTWithID("first", now.Add(time.Hour)), nil).Once()
+	authAPIMock.EXPECT().GenerateToken(mock.Anything, int32(3600)).Return(testJWTWithID("second", now.Add(time.Hour)), nil).Once()
+	cache := newTokenCache(zap.NewNop())
+
+	_, err := cache.get("ns/secret", "1", cache.generate(context.Background(), "ns/secret", authAPIMock))
+	require.NoError(t, err)
+	_, err = cache.get("ns/secret", "2", cache.generate(context.Background(), "ns/secret", authAPIMock))
+	require.NoError(t, err)
+	require.Empty(t, cache.generated)
+
+	authAPIMock.EXPECT().DeleteToken(mock.Anything, mock.Anything).Return(nil).Twice()
+	cache.revokeAll()
+}
+
+func TestTokenCacheInvalidate(t *testing.T) {
+	now := time.Now()
+	authAPIMock := mocks.NewAuthAPI(t)
+	cache := newTokenCache(zap.NewNop())
+	issue := func(token string) func() (issuedToken, error) {
+		return func() (issuedToken, error) {
+			return issuedToken{token: token, expiresAt: now.Add(time.Hour), authAPI: authAPIMock}, nil
+		}
+	}
+
+	_, err := cache.get("ns/secret", "1", issue("current"))
+	require.NoError(t, err)
+	cache.invalidate("ns/secret", "rejected")
+	token, ok := cache.lookup("ns/secret", "1")
+	require.True(t, ok, "a token other than the rejected one is kept")
+	require.Equal(t, "current", token)
+
+	cache.invalidate("ns/secret", "current")
+	_, ok = cache.lookup("ns/secret", "1")
+	require.False(t, ok, "the rejected token is dropped")
+	require.Empty(t, cache.retired, "the rejected token is not kept for deletion")
+}
+
+func TestTokenCacheSweepsStaleTokens(t *testing.T) {
+	now := time.Now()
+	authAPIMock := mocks.NewAuthAPI(t)
+	authAPIMock.EXPECT().GenerateToken(mock.Anything, int32(3600)).Return(testJWTWithID("current", now.Add(time.Hour)), nil).Once()
+	authAPIMock.EXPECT().GetTokens(mock.Anything).Return([]ionoscloud_auth.Token{
+		*ionoscloud_auth.NewToken("expired-webhook-token", "", "", ""),
+		*ionoscloud_auth.NewToken("valid-webhook-token", "", "", ""),
+		*ionoscloud_auth.NewToken("expired-user-token", "", "", ""),
+		*ionoscloud_auth.NewToken("current", "", "", ""),
+	}, nil).Once()
+	swept := make(chan struct{})
+	authAPIMock.EXPECT().DeleteToken(mock.Anything, "expired-webhook-token").Return(nil).Run(func(_ context.Context, _ string) { close(swept) }).Once()
+	cache := newTokenCache(zap.NewNop())
+	cache.sweep = true
+	cache.generated["ns/secret"] = map[string]time.Time{
+		"expired-webhook-token": now.Add(-time.Hour),
+		"valid-webhook-token":   now.Add(30 * time.Minute),
+		"deleted-webhook-token": now.Add(-time.Hour),
+	}
+
+	_, err := cache.get("ns/secret", "1", cache.generate(context.Background(), "ns/secret", authAPIMock))
+	require.NoError(t, err)
+	select {
+	case <-swept:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stale token was not swept")
+	}
+	require.Eventually(t, func() bool {
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+		_, expired := cache.generated["ns/secret"]["expired-webhook-token"]
+		_, deleted := cache.generated["ns/secret"]["deleted-webhook-token"]
+		return !expired && !deleted && len(cache.generated["ns/secret"]) == 2
+	}, 5*time.Second, 10*time.Millisecond, "swept and missing tokens should be forgotten")
+}
+
+func testJWT(expiresAt time.Time) string {
+	return testJWTWithID("", expiresAt)
+}
+
+func testJWTWithID(id string, expiresAt time.Time) string {
+	encode := base64.RawURLEncoding.EncodeToString
+	return encode([]byte(fmt.Sprintf(`{"alg":"RS256","typ":"JWT","kid":%q}`, id))) + "." +
+		encode([]byte(fmt.Sprintf(`{"exp":%d}`, expiresAt.Unix()))) + ".signature"
+}
@@ -0,0 +1,60 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"go.uber.org/zap"
+)
+
+// acmeChallengeLabel is the label prepended by ACME to the domain of a DNS-01 challenge.
+const acmeChallengeLabel = "_acme-challenge."
+
+// validationChallenge returns the challenge with its FQDN moved into the validation zone, where the record is written
+// instead of the zone of the domain. The FQDN of the domain delegates the challenge to the validation zone with a
+// CNAME record. An FQDN already in the validation zone, e.g. as cert-manager followed the CNAME, is kept.
+func validationChallenge(ch *v1alpha1.ChallengeRequest, validationZone string) *v1alpha1.ChallengeRequest {
+	fqdn := strings.ToLower(strings.TrimSuffix(ch.ResolvedFQDN, "."))
+	if !inZone(fqdn, validationZone) {
+		fqdn = strings.TrimPrefix(fqdn, acmeChallengeLabel) + "." + validationZone
+	}
+	delegated := ch.DeepCopy()
+	delegated.ResolvedFQDN = fqdn + "."
+	delegated.ResolvedZone = validationZone + "."
+	return delegated
+}
+
+// checkDelegation verifies that the FQDN of the challenge is a CNAME of the FQDN in the validation zone, and returns
+// the missing CNAME record otherwise.
+func (s *ionosCloudDnsProviderResolver) checkDelegation(ctx context.Context, ch *v1alpha1.ChallengeRequest,
+	delegated *v1alpha1.ChallengeRequest,
+) error {
+	fqdn := strings.TrimSuffix(ch.ResolvedFQDN, ".")
+	target := strings.TrimSuffix(delegated.ResolvedFQDN, ".")
+	if strings.EqualFold(fqdn, target) {
+		return nil
+	}
+	callCtx, cancel := context.WithTimeout(ctx, apiCallTimeout)
+	cname, err := s.lookupCNAME(callCtx, fqdn+".")
+	cancel()
+	var dnsErr *net.DNSError
+	if err != nil && !(errors.As(err, &dnsErr) && dnsErr.IsNotFound) {
+		return fmt.Errorf("failed to look up the CNAME record of '%s': %w", fqdn, err)
+	}
+	cname = strings.TrimSuffix(cname, ".")
+	if err == nil && strings.EqualFold(cname, target) {
+		s.logger.Debug("challenge is delegated to the validation zone", zap.String("fqdn", fqdn),
+			zap.String("target", target))
+		return nil
+	}
+	missing := fmt.Errorf("missing CNAME record '%s. CNAME %s.' delegating the challenge to the validation zone",
+		fqdn, target)
+	if err == nil && cname != "" && !strings.EqualFold(cname, fqdn) {
+		return fmt.Errorf("%w, '%s' resolves to '%s' instead", missing, fqdn, cname)
+	}
+	return missing
+}
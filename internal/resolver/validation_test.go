@@ -0,0 +1,111 @@
+//go:build unit
+
+package resolver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestValidationChallenge(t *testing.T) {
+	testCases := []struct {
+		whenFQDN string
+		thenFQDN string
+	}{
+		{whenFQDN: "_acme-challenge.www.example.com.", thenFQDN: "www.example.com.acme.example.net."},
+		{whenFQDN: "_acme-challenge.Example.com.", thenFQDN: "example.com.acme.example.net."},
+		{whenFQDN: "www.example.com.acme.example.net.", thenFQDN: "www.example.com.acme.example.net."},
+	}
+	for _, tc := range testCases {
+		ch := &v1alpha1.ChallengeRequest{ResolvedFQDN: tc.whenFQDN, ResolvedZone: "example.com."}
+
+		delegated := validationChallenge(ch, "acme.example.net")
+
+		require.Equal(t, tc.thenFQDN, delegated.ResolvedFQDN, tc.whenFQDN)
+		require.Equal(t, "acme.example.net.", delegated.ResolvedZone, tc.whenFQDN)
+		require.Equal(t, tc.whenFQDN, ch.ResolvedFQDN, tc.whenFQDN)
+	}
+}
+
+func TestCheckDelegation(t *testing.T) {
+	notFound := &net.DNSError{Err: "no such host", Name: "_acme-challenge.www.example.com.", IsNotFound: true}
+	testCases := []struct {
+		name          string
+		whenFQDN      string
+		givenCNAME    string
+		givenErr      error
+		thenLookups   int
+		thenErrorText string
+	}{
+		{
+			name:        "cname points to the validation zone",
+			whenFQDN:    "_acme-challenge.www.example.com.",
+			givenCNAME:  "www.example.com.acme.example.net.",
+			thenLookups: 1,
+		},
+		{
+			name:        "fqdn already in the validation zone",
+			whenFQDN:    "www.example.com.acme.example.net.",
+			thenLookups: 0,
+		},
+		{
+			name:        "cname is missing",
+			whenFQDN:    "_acme-challenge.www.example.com.",
+			givenErr:    notFound,
+			thenLookups: 1,
+			thenErrorText: "missing CNAME record '_acme-challenge.www.example.com. CNAME www.example.com.acme.example.net.' " +
+				"delegating the challenge to the validation zone",
+		},
+		{
+			name:        "name without cname",
+			whenFQDN:    "_acme-challenge.www.example.com.",
+			givenCNAME:  "_acme-challenge.www.example.com.",
+			thenLookups: 1,
+			thenErrorText: "missing CNAME record '_acme-challenge.www.example.com. CNAME www.example.com.acme.example.net.' " +
+				"delegating the challenge to the validation zone",
+		},
+		{
+			name:        "cname points elsewhere",
+			whenFQDN:    "_acme-challenge.www.example.com.",
+			givenCNAME:  "www.example.com.acme.example.org.",
+			thenLookups: 1,
+			thenErrorText: "missing CNAME record '_acme-challenge.www.example.com. CNAME www.example.com.acme.example.net.' " +
+				"delegating the challenge to the validation zone, '_acme-challenge.www.example.com' resolves to " +
+				"'www.example.com.acme.example.org' instead",
+		},
+		{
+			name:          "lookup fails",
+			whenFQDN:      "_acme-challenge.www.example.com.",
+			givenErr:      errors.New("server misbehaving"),
+			thenLookups:   1,
+			thenErrorText: "failed to look up the CNAME record of '_acme-challenge.www.example.com': server misbehaving",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resolver := NewResolver(testNamespace, nil, nil, nil, zap.NewNop()).(*ionosCloudDnsProviderResolver)
+			lookups := 0
+			resolver.lookupCNAME = func(_ context.Context, host string) (string, error) {
+				lookups++
+				require.Equal(t, tc.whenFQDN, host)
+				return tc.givenCNAME, tc.givenErr
+			}
+			ch := &v1alpha1.ChallengeRequest{ResolvedFQDN: tc.whenFQDN}
+
+			err := resolver.checkDelegation(context.Background(), ch, validationChallenge(ch, "acme.example.net"))
+
+			if tc.thenErrorText != "" {
+				require.EqualError(t, err, tc.thenErrorText)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tc.thenLookups, lookups)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/ionos-cloud/cert-manager-webhook-ionos-cloud/internal/vault"
+)
+
+const (
+	defaultVaultMount     = "secret"
+	defaultVaultKVVersion = 2
+)
+
+// vaultPathSegmentPattern matches the segments allowed in the mount and path of a Vault secret.
+var vaultPathSegmentPattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// vaultSecretConfig references a secret of a Vault KV secrets engine which contains the credentials.
+type vaultSecretConfig struct {
+	Mount     string `json:"mount"`
+	Path      string `json:"path"`
+	KVVersion int    `json:"kvVersion"`
+}
+
+// WithVault allows the solver config to read the credentials from Vault, from the secrets below the allowed paths.
+// An allowed path is the mount followed by a path prefix, e.g. secret/ionos-cloud.
+func WithVault(vaultAPI vault.VaultAPI, allowedPaths []string) Option {
+	return func(s *ionosCloudDnsProviderResolver) {
+		s.vaultAPI = vaultAPI
+		for _, allowedPath := range allowedPaths {
+			if allowedPath = strings.Trim(strings.TrimSpace(allowedPath), "/"); allowedPath != "" {
+				s.vaultAllowedPaths = append(s.vaultAllowedPaths, allowedPath)
+			}
+		}
+	}
+}
+
+// credentialsFromVault reads the credentials from the Vault KV secret, using the same keys as for a Kubernetes
+// secret. The version of a KV v2 secret is used as the version of the credentials.
+func (s *ionosCloudDnsProviderResolver) credentialsFromVault(ctx context.Context,
+	config credentialsConfig,
+) (credentials, error) {
+	if s.vaultAPI == nil {
+		return credentials{}, fmt.Errorf("vault is not configured for the webhook")
+	}
+	vaultConfig := *config.Vault
+	if vaultConfig.Path == "" {
+		return credentials{}, fmt.Errorf("vault path must be set")
+	}
+	if vaultConfig.Mount == "" {
+		vaultConfig.Mount = defaultVaultMount
+	}
+	if vaultConfig.KVVersion == 0 {
+		vaultConfig.KVVersion = defaultVaultKVVersion
+	}
+	if vaultConfig.KVVersion != 1 && vaultConfig.KVVersion != 2 {
+		return credentials{}, fmt.Errorf("unsupported vault kvVersion %d", vaultConfig.KVVersion)
+	}
+	if err := s.checkVaultPath(vaultConfig.Mount, vaultConfig.Path); err != nil {
+		return credentials{}, err
+	}
+	config.applyDefaultKeys()
+
+	secret, err := s.vaultAPI.ReadSecret(ctx, vaultConfig.Mount, vaultConfig.Path, vaultConfig.KVVersion)
+	if err != nil {
+		return credentials{}, err
+	}
+	return credentials{
+		source:   "vault/" + vaultConfig.Mount + "/" + vaultConfig.Path,
+		version:  secret.Version,
+		token:    secret.Data[config.AuthTokenSecretKey],
+		username: secret.Data[config.UsernameSecretKey],
+		password: secret.Data[config.PasswordSecretKey],
+	}, nil
+}
+
+// checkVaultPath verifies that the secret is below one of the allowed paths. Segments may only contain letters,
+// digits, '.', '_' and '-', and . and .. segments are rejected, so that the path can not escape the allowed prefix.
+func (s *ionosCloudDnsProviderResolver) checkVaultPath(mount, path string) error {
+	fullPath := mount + "/" + path
+	for _, segment := range strings.Split(fullPath, "/") {
+		if !vaultPathSegmentPattern.MatchString(segment) || segment == "." || segment == ".." {
+			return fmt.Errorf("invalid vault path '%s'", fullPath)
+		}
+	}
+	if !slices.ContainsFunc(s.vaultAllowedPaths, func(allowedPath string) bool {
+		return fullPath == allowedPath || strings.HasPrefix(fullPath, allowedPath+"/")
+	}) {
+		return fmt.Errorf("vault path '%s' is not allowed", fullPath)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+//go:build unit
+
+package resolver
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/ionos-cloud/cert-manager-webhook-ionos-cloud/internal/mocks"
+	"github.com/ionos-cloud/cert-manager-webhook-ionos-cloud/internal/vault"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestVaultCredentials(t *testing.T) {
+	errVault := errors.New("vault failed")
+	testCases := []struct {
+		name                  string
+		givenConfig           vaultSecretConfig
+		givenKeys             credentialsConfig
+		givenSecret           *vault.Secret
+		givenError            error
+		whenAmbientNotAllowed bool
+		thenMount             string
+		thenKVVersion         int
+		thenCreds             credentials
+		thenErrorText         string
+	}{
+		{
+			name:          "kv v2 with defaults",
+			givenConfig:   vaultSecretConfig{Path: "ionos"},
+			givenSecret:   &vault.Secret{Data: map[string]string{"auth-token": "vault-token"}, Version: "3"},
+			thenMount:     "secret",
+			thenKVVersion: 2,
+			thenCreds:     credentials{source: "vault/secret/ionos", version: "3", token: "vault-token"},
+		},
+		{
+			name:          "kv v1 with custom keys",
+			givenConfig:   vaultSecretConfig{Mount: "kv", Path: "ionos", KVVersion: 1},
+			givenKeys:     credentialsConfig{UsernameSecretKey: "user", PasswordSecretKey: "pass"},
+			givenSecret:   &vault.Secret{Data: map[string]string{"user": "u", "pass": "p"}},
+			thenMount:     "kv",
+			thenKVVersion: 1,
+			thenCreds:     credentials{source: "vault/kv/ionos", username: "u", password: "p"},
+		},
+		{
+			name:          "read error",
+			givenConfig:   vaultSecretConfig{Path: "ionos"},
+			givenError:    errVault,
+			thenMount:     "secret",
+			thenKVVersion: 2,
+			thenErrorText: "vault failed",
+		},
+		{
+			name:          "missing path",
+			givenConfig:   vaultSecretConfig{},
+			thenErrorText: "vault path must be set",
+		},
+		{
+			name:          "unsupported kv version",
+			givenConfig:   vaultSecretConfig{Path: "ionos", KVVersion: 3},
+			thenErrorText: "unsupported vault kvVersion 3",
+		},
+		{
+			name:          "path below an allowed path",
+			givenConfig:   vaultSecretConfig{Path: "ionos/dns"},
+			givenSecret:   &vault.Secret{Data: map[string]string{"auth-token": "vault-token"}},
+			thenMount:     "secret",
+			thenKVVersion: 2,
+			thenCreds:     credentials{source: "vault/secret/ionos/dns", token: "vault-token"},
+		},
+		{
+			name:          "path not allowed",
+			givenConfig:   vaultSecretConfig{Path: "other"},
+			thenErrorText: "vault path 'secret/other' is not allowed",
+		},
+		{
+			name:          "path sharing the prefix of an allowed path",
+			givenConfig:   vaultSecretConfig{Path: "ionos-other"},
+			thenErrorText: "vault path 'secret/ionos-other' is not allowed",
+		},
+		{
+			name:          "mount not allowed",
+			givenConfig:   vaultSecretConfig{Mount: "other", Path: "ionos"},
+			thenErrorText: "vault path 'other/ionos' is not allowed",
+		},
+		{
+			name:          "path escaping the allowed path",
+			givenConfig:   vaultSecretConfig{Path: "ionos/../other"},
+			thenErrorText: "invalid vault path 'secret/ionos/../other'",
+		},
+		{
+			name:          "path with encoded segments",
+			givenConfig:   vaultSecretConfig{Path: "ionos/%2e%2e/other"},
+			thenErrorText: "invalid vault path 'secret/ionos/%2e%2e/other'",
+		},
+		{
+			name:          "path with query",
+			givenConfig:   vaultSecretConfig{Path: "ionos/dns?version=1"},
+			thenErrorText: "invalid vault path 'secret/ionos/dns?version=1'",
+		},
+		{
+			name:                  "ambient credentials not allowed for the issuer",
+			givenConfig:           vaultSecretConfig{Path: "ionos"},
+			whenAmbientNotAllowed: true,
+			thenErrorText:         "vault can only be used by issuers allowed to use ambient credentials",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			vaultAPIMock := mocks.NewVaultAPI(t)
+			if tc.thenMount != "" {
+				vaultAPIMock.EXPECT().ReadSecret(mock.Anything, tc.thenMount, tc.givenConfig.Path, tc.thenKVVersion).
+					Return(tc.givenSecret, tc.givenError).Once()
+			}
+			resolver := NewResolver(testNamespace, nil, nil, nil, zap.NewNop(),
+				WithVault(vaultAPIMock, []string{"secret/ionos", "/kv/"})).(*ionosCloudDnsProviderResolver)
+			config := tc.givenKeys
+			config.Vault = &tc.givenConfig
+
+			challenge := &v1alpha1.ChallengeRequest{AllowAmbientCredentials: !tc.whenAmbientNotAllowed}
+			creds, err := resolver.resolveCredentials(context.Background(), challenge, config)
+			if tc.thenErrorText != "" {
+				require.ErrorContains(t, err, tc.thenErrorText)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.thenCreds, creds)
+		})
+	}
+}
+
+func TestVaultNoAllowedPaths(t *testing.T) {
+	resolver := NewResolver(testNamespace, nil, nil, nil, zap.NewNop(),
+		WithVault(mocks.NewVaultAPI(t), []string{""})).(*ionosCloudDnsProviderResolver)
+	_, err := resolver.resolveCredentials(context.Background(),
+		&v1alpha1.ChallengeRequest{AllowAmbientCredentials: true},
+		credentialsConfig{Vault: &vaultSecretConfig{Path: "ionos"}})
+	require.ErrorContains(t, err, "vault path 'secret/ionos' is not allowed")
+}
+
+func TestVaultNotConfigured(t *testing.T) {
+	resolver := NewResolver(testNamespace, nil, nil, nil, zap.NewNop()).(*ionosCloudDnsProviderResolver)
+	_, err := resolver.resolveCredentials(context.Background(),
+		&v1alpha1.ChallengeRequest{AllowAmbientCredentials: true},
+		credentialsConfig{Vault: &vaultSecretConfig{Path: "ionos"}})
+	require.ErrorContains(t, err, "vault is not configured for the webhook")
+}
@@ -0,0 +1,98 @@
+package resolver
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ionos-cloud/cert-manager-webhook-ionos-cloud/internal/clouddns"
+	"go.uber.org/zap"
+)
+
+// WithZoneCache caches the zone IDs looked up by name per credentials source for the given TTL, and the names
+// without a zone for the negative TTL. A TTL of zero disables the respective caching.
+func WithZoneCache(ttl, negativeTTL time.Duration) Option {
+	return func(s *ionosCloudDnsProviderResolver) {
+		s.zoneCache.ttl = ttl
+		s.zoneCache.negativeTTL = negativeTTL
+	}
+}
+
+// withCachedZone runs a challenge step, which returns the zone it used. If the zone was taken from the cache but is
+// not found anymore, e.g. because it was deleted and recreated, the entry is evicted and the step runs again with a
+// fresh lookup.
+func (s *ionosCloudDnsProviderResolver) withCachedZone(source string, step func() (dnsZone, error)) error {
+	zone, err := step()
+	if zone.cached && clouddns.IsNotFound(err) {
+		s.logger.Info("cached zone not found, looking it up again", zap.String("zoneName", zone.name),
+			zap.String("zoneId", zone.id))
+		s.zoneCache.evict(source, zone.name)
+		_, err = step()
+	}
+	return err
+}
+
+// zoneCache keeps the results of the zone lookups by name per credentials source, as the zones of an account rarely
+// change, while every challenge looks them up in Present and CleanUp. An empty zone ID caches that there is no zone
+// with the name.
+type zoneCache struct {
+	mu          sync.Mutex
+	ttl         time.Duration
+	negativeTTL time.Duration
+	entries     map[string]map[string]cachedZone
+	now         func() time.Time
+}
+
+type cachedZone struct {
+	id        string
+	expiresAt time.Time
+}
+
+func newZoneCache() *zoneCache {
+	return &zoneCache{
+		entries: make(map[string]map[string]cachedZone),
+		now:     time.Now,
+	}
+}
+
+// get returns the cached zone ID for the name, which is empty if there is no such zone. ok is false if the name is
+// not cached or the entry expired.
+func (c *zoneCache) get(source, zoneName string) (id string, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[source][zoneName]
+	if !ok || !c.now().Before(entry.expiresAt) {
+		return "", false
+	}
+	return entry.id, true
+}
+
+// put caches the zone ID for the name, or that there is no zone with the name if the ID is empty.
+func (c *zoneCache) put(source, zoneName, id string) {
+	ttl := c.ttl
+	if id == "" {
+		ttl = c.negativeTTL
+	}
+	if ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries[source] == nil {
+		c.entries[source] = make(map[string]cachedZone)
+	}
+	c.entries[source][zoneName] = cachedZone{id: id, expiresAt: c.now().Add(ttl)}
+}
+
+// evict removes the entry for the name, e.g. when the cached zone was not found anymore.
+func (c *zoneCache) evict(source, zoneName string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries[source], zoneName)
+}
+
+// evictSource removes all the entries of the credentials source, e.g. when its credentials change.
+func (c *zoneCache) evictSource(source string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, source)
+}
@@ -0,0 +1,90 @@
+//go:build unit
+
+package resolver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestZoneCache(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	testCases := []struct {
+		name        string
+		givenTTL    time.Duration
+		givenID     string
+		whenSource  string
+		whenElapsed time.Duration
+		whenEvicted bool
+		thenID      string
+		thenOK      bool
+	}{
+		{
+			name:        "zone id is cached",
+			givenTTL:    time.Minute,
+			givenID:     "zone-id",
+			whenSource:  "ns/secret",
+			whenElapsed: 59 * time.Second,
+			thenID:      "zone-id",
+			thenOK:      true,
+		},
+		{
+			name:        "zone id expires",
+			givenTTL:    time.Minute,
+			givenID:     "zone-id",
+			whenSource:  "ns/secret",
+			whenElapsed: time.Minute,
+		},
+		{
+			name:        "missing zone is cached for the negative ttl",
+			givenTTL:    time.Minute,
+			whenSource:  "ns/secret",
+			whenElapsed: 9 * time.Second,
+			thenOK:      true,
+		},
+		{
+			name:        "missing zone expires after the negative ttl",
+			givenTTL:    time.Minute,
+			whenSource:  "ns/secret",
+			whenElapsed: 10 * time.Second,
+		},
+		{
+			name:       "zones are cached per source",
+			givenTTL:   time.Minute,
+			givenID:    "zone-id",
+			whenSource: "ns/other-secret",
+		},
+		{
+			name:        "evicted source",
+			givenTTL:    time.Minute,
+			givenID:     "zone-id",
+			whenSource:  "ns/secret",
+			whenEvicted: true,
+		},
+		{
+			name:       "caching disabled",
+			givenID:    "zone-id",
+			whenSource: "ns/secret",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cache := newZoneCache()
+			cache.ttl = tc.givenTTL
+			cache.negativeTTL = 10 * time.Second
+			cache.now = func() time.Time { return now }
+			cache.put("ns/secret", "example.com", tc.givenID)
+			if tc.whenEvicted {
+				cache.evictSource("ns/secret")
+			}
+
+			cache.now = func() time.Time { return now.Add(tc.whenElapsed) }
+			id, ok := cache.get(tc.whenSource, "example.com")
+
+			require.Equal(t, tc.thenOK, ok)
+			require.Equal(t, tc.thenID, id)
+		})
+	}
+}
@@ -0,0 +1,328 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/ionos-cloud/cert-manager-webhook-ionos-cloud/internal/clouddns"
+	ionoscloud "github.com/ionos-cloud/sdk-go-dns"
+	"go.uber.org/zap"
+)
+
+// dnsZone is the IONOS Cloud zone the record of a challenge is written to.
+type dnsZone struct {
+	id   string
+	name string
+	// cached is set if the ID was taken from the zone cache.
+	cached bool
+}
+
+// zoneConfig sets the IONOS Cloud zone the challenge records are written to, instead of looking it up by the
+// challenge FQDN, e.g. for split-horizon setups.
+type zoneConfig struct {
+	// ZoneID skips the lookup of the zone. If ZoneName is not set, the name is read from the zone.
+	ZoneID string `json:"zoneId"`
+	// ZoneName is looked up instead of the domains of the challenge FQDN.
+	ZoneName string `json:"zoneName"`
+	// CreateZoneIfMissing creates the zone in Present if it is not found. The zone is named ZoneName, or otherwise
+	// after the DNS name of the challenge, and must be allowed by the webhook. Only this zone is looked up, so that
+	// it is also created if a parent zone exists.
+	CreateZoneIfMissing bool `json:"createZoneIfMissing"`
+	// DeleteCreatedZone deletes the zone in CleanUp if it was created by the webhook and has no records left.
+	DeleteCreatedZone bool `json:"deleteCreatedZone"`
+	// ValidationZone is the zone all the challenge records are written to, under the name of the challenge domain,
+	// e.g. www.example.com.acme.example.net for _acme-challenge.www.example.com. The challenge FQDN must be a CNAME
+	// of this name.
+	ValidationZone string `json:"validationZone"`
+}
+
+// zoneCreatedDescription marks the zones created by the webhook, which may be deleted on clean up.
+const zoneCreatedDescription = "created by cert-manager-webhook-ionos-cloud"
+
+// WithZoneCreation allows the solver config to create the zones with one of the given suffixes, i.e. the suffixes
+// themselves and their subdomains.
+func WithZoneCreation(allowedSuffixes []string) Option {
+	return func(s *ionosCloudDnsProviderResolver) {
+		for _, suffix := range allowedSuffixes {
+			s.zoneCreationSuffixes = append(s.zoneCreationSuffixes,
+				strings.ToLower(strings.TrimSuffix(strings.TrimSpace(suffix), ".")))
+		}
+	}
+}
+
+// findZone returns the zone of the solver config, or looks up the longest IONOS Cloud zone containing the FQDN of
+// the challenge. The zone resolved by cert-manager is not used, as it may differ from the zones in IONOS Cloud for
+// delegated subzones. If zones are created, only the zone to create is looked up.
+func (s *ionosCloudDnsProviderResolver) findZone(ctx context.Context, ch *v1alpha1.ChallengeRequest, config zoneConfig,
+	source string, shouldFind bool, client clouddns.DNSAPI,
+) (dnsZone, error) {
+	fqdn := strings.TrimSuffix(ch.ResolvedFQDN, ".")
+	if config.ZoneID != "" || config.ZoneName != "" {
+		return s.configuredZone(ctx, fqdn, config, source, shouldFind, client)
+	}
+	if config.CreateZoneIfMissing {
+		return s.getZoneByName(ctx, source, zoneToCreate(ch, config), client)
+	}
+	// zone names are lowercase in IONOS Cloud
+	zone, err := s.longestZone(ctx, source, zoneCandidates(strings.ToLower(fqdn)), client)
+	if err != nil || zone.id != "" {
+		return zone, err
+	}
+
+	if shouldFind {
+		return dnsZone{}, fmt.Errorf("no zone found for '%s'", fqdn)
+	}
+	return dnsZone{}, nil
+}
+
+// configuredZone returns the zone set in the solver config. The zone is only read from the API if its ID or its name
+// is missing, and must contain the challenge FQDN.
+func (s *ionosCloudDnsProviderResolver) configuredZone(ctx context.Context, fqdn string, config zoneConfig,
+	source string, shouldFind bool, client clouddns.DNSAPI,
+) (dnsZone, error) {
+	zone := dnsZone{id: config.ZoneID, name: config.ZoneName}
+	if zone.name != "" && !inZone(fqdn, zone.name) {
+		return dnsZone{}, fmt.Errorf("challenge FQDN '%s' is not in the configured zone '%s'", fqdn, zone.name)
+	}
+	switch {
+	case zone.id != "" && zone.name != "":
+		return zone, nil
+	case zone.id != "":
+		s.logger.Debug("get zone...", zap.String("zoneId", zone.id))
+		callCtx, cancel := context.WithTimeout(ctx, apiCallTimeout)
+		zoneRead, err := client.GetZone(callCtx, zone.id)
+		cancel()
+		if clouddns.IsNotFound(err) && !shouldFind {
+			return dnsZone{}, nil
+		}
+		if err != nil {
+			s.logger.Error("Error fetching zone", zap.Error(err))
+			return dnsZone{}, err
+		}
+		if zoneName := zoneRead.GetProperties().GetZoneName(); zoneName != nil {
+			zone.name = strings.ToLower(*zoneName)
+		}
+		if !inZone(fqdn, zone.name) {
+			return dnsZone{}, fmt.Errorf("challenge FQDN '%s' is not in the configured zone '%s' (%s)", fqdn,
+				zone.name, zone.id)
+		}
+		return zone, nil
+	default:
+		zone, err := s.getZoneByName(ctx, source, zone.name, client)
+		if err == nil && zone.id == "" && shouldFind {
+			return dnsZone{}, fmt.Errorf("configured zone '%s' not found", config.ZoneName)
+		}
+		return zone, err
+	}
+}
+
+// createZone creates the zone for the challenge and waits until it is AVAILABLE. The nameservers of the zone are
+// logged, as the zone must be delegated to them before the challenge record can be resolved.
+func (s *ionosCloudDnsProviderResolver) createZone(ctx context.Context, ch *v1alpha1.ChallengeRequest, config zoneConfig,
+	source string, client clouddns.DNSAPI,
+) (dnsZone, error) {
+	if config.ZoneID != "" {
+		return dnsZone{}, fmt.Errorf("configured zone %s not found", config.ZoneID)
+	}
+	zoneName := zoneToCreate(ch, config)
+	if !s.zoneCreationAllowed(zoneName) {
+		return dnsZone{}, fmt.Errorf("zone '%s' not found, and creating it is not allowed", zoneName)
+	}
+	fqdn := strings.TrimSuffix(ch.ResolvedFQDN, ".")
+	if !inZone(fqdn, zoneName) {
+		return dnsZone{}, fmt.Errorf("challenge FQDN '%s' is not in the zone '%s' to create", fqdn, zoneName)
+	}
+
+	s.logger.Info("zone not found, creating zone...", zap.String("zoneName", zoneName))
+	zoneCreate := *ionoscloud.NewZone(zoneName)
+	zoneCreate.SetDescription(zoneCreatedDescription)
+	callCtx, cancel := context.WithTimeout(ctx, apiCallTimeout)
+	created, err := client.CreateZone(callCtx, zoneCreate)
+	cancel()
+	if err != nil {
+		// the zone may have been created by a concurrent challenge for the same zone
+		s.zoneCache.evict(source, zoneName)
+		if zone, lookupErr := s.getZoneByName(ctx, source, zoneName, client); lookupErr == nil && zone.id != "" {
+			return zone, nil
+		}
+		s.logger.Error("Error creating zone", zap.Error(err))
+		return dnsZone{}, err
+	}
+	zone := dnsZone{id: *created.Id, name: zoneName}
+	created, err = s.waitForZoneAvailable(ctx, client, zone.id)
+	if err != nil {
+		return dnsZone{}, err
+	}
+	var nameservers []string
+	if created.Metadata != nil && created.Metadata.Nameservers != nil {
+		nameservers = *created.Metadata.Nameservers
+	}
+	s.zoneCache.put(source, zoneName, zone.id)
+	s.logger.Info("zone created, it must be delegated to the IONOS Cloud nameservers", zap.String("zoneName", zoneName),
+		zap.String("zoneId", zone.id), zap.Strings("nameservers", nameservers))
+	return zone, nil
+}
+
+// deleteCreatedZone deletes the zone if it was created by the webhook and has no records left.
+func (s *ionosCloudDnsProviderResolver) deleteCreatedZone(ctx context.Context, source string, zone dnsZone,
+	client clouddns.DNSAPI,
+) error {
+	callCtx, cancel := context.WithTimeout(ctx, apiCallTimeout)
+	zoneRead, err := client.GetZone(callCtx, zone.id)
+	cancel()
+	if err != nil {
+		return err
+	}
+	description := zoneRead.GetProperties().GetDescription()
+	if description == nil || *description != zoneCreatedDescription {
+		s.logger.Debug("zone was not created by the webhook, keeping it", zap.String("zoneId", zone.id))
+		return nil
+	}
+	callCtx, cancel = context.WithTimeout(ctx, apiCallTimeout)
+	recordList, err := client.ListRecords(callCtx, zone.id, clouddns.RecordFilter{})
+	cancel()
+	if err != nil {
+		return err
+	}
+	for _, record := range derefRecords(recordList) {
+		if !isZoneRecord(record) && recordState(record) != ionoscloud.PROVISIONINGSTATE_DESTROYING {
+			s.logger.Info("created zone still has records, keeping it", zap.String("zoneName", zone.name),
+				zap.String("zoneId", zone.id))
+			return nil
+		}
+	}
+	s.logger.Info("deleting created zone...", zap.String("zoneName", zone.name), zap.String("zoneId", zone.id))
+	callCtx, cancel = context.WithTimeout(ctx, apiCallTimeout)
+	err = client.DeleteZone(callCtx, zone.id)
+	cancel()
+	if err != nil {
+		s.logger.Error("Error deleting zone", zap.Error(err))
+		return err
+	}
+	s.zoneCache.evict(source, zone.name)
+	s.logger.Info("created zone successfully deleted", zap.String("zoneName", zone.name), zap.String("zoneId", zone.id))
+	return nil
+}
+
+// zoneToCreate returns the name of the zone created for the challenge, ZoneName or otherwise the DNS name.
+func zoneToCreate(ch *v1alpha1.ChallengeRequest, config zoneConfig) string {
+	if config.ZoneName != "" {
+		return config.ZoneName
+	}
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSuffix(ch.DNSName, "."), "*."))
+}
+
+func (s *ionosCloudDnsProviderResolver) zoneCreationAllowed(zoneName string) bool {
+	for _, suffix := range s.zoneCreationSuffixes {
+		if zoneName == suffix || strings.HasSuffix(zoneName, "."+suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isZoneRecord reports whether the record is an NS record of the zone apex, which is managed with the zone.
+func isZoneRecord(record ionoscloud.RecordRead) bool {
+	name, recordType := record.GetProperties().GetName(), record.GetProperties().GetType()
+	if name == nil || recordType == nil {
+		return false
+	}
+	return (*name == "@" || *name == "") && *recordType == ionoscloud.RECORDTYPE_NS
+}
+
+// getZoneByName returns the zone with the given name, or an empty zone if there is none. The result is taken from
+// and added to the zone cache of the credentials source.
+func (s *ionosCloudDnsProviderResolver) getZoneByName(ctx context.Context, source string, zoneName string,
+	client clouddns.DNSAPI,
+) (dnsZone, error) {
+	return s.longestZone(ctx, source, []string{zoneName}, client)
+}
+
+// longestZone returns the first of the candidate zone names, ordered from the longest to the shortest, which exists,
+// or an empty zone if there is none. The zones are listed with a single call filtered by the shortest candidate,
+// which matches all the others as the API filters by a partial name, unless all candidates are cached. The results
+// are added to the zone cache of the credentials source.
+func (s *ionosCloudDnsProviderResolver) longestZone(ctx context.Context, source string, candidates []string,
+	client clouddns.DNSAPI,
+) (dnsZone, error) {
+	if len(candidates) == 0 {
+		return dnsZone{}, nil
+	}
+	if zone, ok := s.cachedZone(source, candidates); ok {
+		return zone, nil
+	}
+	filter := candidates[len(candidates)-1]
+	s.logger.Debug("find zone...", zap.String("zoneName", filter))
+	callCtx, cancel := context.WithTimeout(ctx, apiCallTimeout)
+	zoneList, err := client.GetZones(callCtx, filter)
+	cancel()
+	if err != nil {
+		s.logger.Error("Error fetching zone", zap.Error(err))
+		return dnsZone{}, err
+	}
+	ids := make(map[string]string)
+	for _, zone := range derefZones(zoneList) {
+		if zone.Id != nil && zone.Properties != nil && zone.Properties.ZoneName != nil {
+			ids[strings.ToLower(*zone.Properties.ZoneName)] = *zone.Id
+		}
+	}
+	var found dnsZone
+	for _, zoneName := range candidates {
+		id := ids[zoneName]
+		s.zoneCache.put(source, zoneName, id)
+		if id != "" && found.id == "" {
+			s.logger.Info("zone found", zap.String("zoneName", zoneName), zap.String("zoneId", id))
+			found = dnsZone{id: id, name: zoneName}
+		}
+	}
+	return found, nil
+}
+
+// cachedZone returns the first existing zone of the candidates from the zone cache. It is only found if the
+// candidates before it are cached as well, and no zone is found only if all candidates are cached.
+func (s *ionosCloudDnsProviderResolver) cachedZone(source string, candidates []string) (dnsZone, bool) {
+	for _, zoneName := range candidates {
+		id, ok := s.zoneCache.get(source, zoneName)
+		if !ok {
+			return dnsZone{}, false
+		}
+		if id != "" {
+			s.logger.Debug("zone lookup cached", zap.String("zoneName", zoneName), zap.String("zoneId", id))
+			return dnsZone{id: id, name: zoneName, cached: true}, true
+		}
+	}
+	s.logger.Debug("zone lookup cached", zap.Strings("zoneNames", candidates))
+	return dnsZone{}, true
+}
+
+// inZone reports whether the FQDN is a subdomain of the zone.
+func inZone(fqdn, zoneName string) bool {
+	return strings.HasSuffix(strings.ToLower(fqdn), "."+zoneName)
+}
+
+// zoneCandidates returns the domains the FQDN may belong to, from the longest to the shortest. Top level domains
+// and the FQDN itself are left out, as they can't be the zone of a challenge record.
+func zoneCandidates(fqdn string) []string {
+	labels := strings.Split(fqdn, ".")
+	candidates := make([]string, 0, len(labels))
+	for i := 1; i < len(labels)-1; i++ {
+		candidates = append(candidates, strings.Join(labels[i:], "."))
+	}
+	return candidates
+}
+
+func derefZones(zoneList ionoscloud.ZoneReadList) []ionoscloud.ZoneRead {
+	if zoneList.Items == nil {
+		return nil
+	}
+	return *zoneList.Items
+}
+
+func derefRecords(recordList ionoscloud.RecordReadList) []ionoscloud.RecordRead {
+	if recordList.Items == nil {
+		return nil
+	}
+	return *recordList.Items
+}
@@ -0,0 +1,334 @@
+//go:build unit
+
+package resolver
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/ionos-cloud/cert-manager-webhook-ionos-cloud/internal/clouddns"
+	"github.com/ionos-cloud/cert-manager-webhook-ionos-cloud/internal/mocks"
+	dnsclient "github.com/ionos-cloud/sdk-go-dns"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestFindZone(t *testing.T) {
+	testCases := []struct {
+		name           string
+		givenZones     map[string]string
+		whenFQDN       string
+		whenDNSName    string
+		whenConfig     zoneConfig
+		whenShouldFind bool
+		thenZone       dnsZone
+		thenRecordName string
+		thenErrorText  string
+	}{
+		{
+			name:           "zone of the fqdn",
+			givenZones:     map[string]string{"example.com": "zone-id"},
+			whenFQDN:       "_acme-challenge.example.com.",
+			thenZone:       dnsZone{id: "zone-id", name: "example.com"},
+			thenRecordName: "_acme-challenge",
+		},
+		{
+			name:           "delegated subzone is preferred",
+			givenZones:     map[string]string{"example.com": "zone-id", "dev.example.com": "subzone-id"},
+			whenFQDN:       "_acme-challenge.www.dev.example.com.",
+			thenZone:       dnsZone{id: "subzone-id", name: "dev.example.com"},
+			thenRecordName: "_acme-challenge.www",
+		},
+		{
+			name:           "parent zone of the fqdn",
+			givenZones:     map[string]string{"example.com": "zone-id"},
+			whenFQDN:       "_acme-challenge.www.dev.example.com.",
+			thenZone:       dnsZone{id: "zone-id", name: "example.com"},
+			thenRecordName: "_acme-challenge.www.dev",
+		},
+		{
+			name:           "zone name is matched case insensitive",
+			givenZones:     map[string]string{"example.com": "zone-id"},
+			whenFQDN:       "_acme-challenge.WWW.Example.com.",
+			thenZone:       dnsZone{id: "zone-id", name: "example.com"},
+			thenRecordName: "_acme-challenge.WWW",
+		},
+		{
+			name:        "zone to create is looked up instead of its parent zone",
+			givenZones:  map[string]string{"preview.example.com": "zone-id"},
+			whenFQDN:    "_acme-challenge.pr-42.preview.example.com.",
+			whenDNSName: "*.pr-42.preview.example.com",
+			whenConfig:  zoneConfig{CreateZoneIfMissing: true},
+			thenZone:    dnsZone{},
+		},
+		{
+			name:           "zone to create exists",
+			givenZones:     map[string]string{"preview.example.com": "zone-id", "pr-42.preview.example.com": "subzone-id"},
+			whenFQDN:       "_acme-challenge.pr-42.preview.example.com.",
+			whenDNSName:    "*.pr-42.preview.example.com",
+			whenConfig:     zoneConfig{CreateZoneIfMissing: true},
+			thenZone:       dnsZone{id: "subzone-id", name: "pr-42.preview.example.com"},
+			thenRecordName: "_acme-challenge",
+		},
+		{
+			name:           "no zone found",
+			givenZones:     map[string]string{"example.org": "zone-id"},
+			whenFQDN:       "_acme-challenge.dev.example.com.",
+			whenShouldFind: true,
+			thenErrorText:  "no zone found for '_acme-challenge.dev.example.com'",
+		},
+		{
+			name:       "no zone to clean up",
+			givenZones: map[string]string{"example.org": "zone-id"},
+			whenFQDN:   "_acme-challenge.dev.example.com.",
+		},
+		{
+			name:           "configured zone id and name",
+			whenFQDN:       "_acme-challenge.www.example.com.",
+			whenConfig:     zoneConfig{ZoneID: "zone-id", ZoneName: "example.com"},
+			thenZone:       dnsZone{id: "zone-id", name: "example.com"},
+			thenRecordName: "_acme-challenge.www",
+		},
+		{
+			name:           "configured zone name",
+			givenZones:     map[string]string{"example.com": "zone-id", "dev.example.com": "subzone-id"},
+			whenFQDN:       "_acme-challenge.www.dev.example.com.",
+			whenConfig:     zoneConfig{ZoneName: "example.com"},
+			thenZone:       dnsZone{id: "zone-id", name: "example.com"},
+			thenRecordName: "_acme-challenge.www.dev",
+		},
+		{
+			name:           "configured zone id",
+			givenZones:     map[string]string{"example.com": "zone-id", "dev.example.com": "subzone-id"},
+			whenFQDN:       "_acme-challenge.www.dev.example.com.",
+			whenConfig:     zoneConfig{ZoneID: "zone-id"},
+			thenZone:       dnsZone{id: "zone-id", name: "example.com"},
+			thenRecordName: "_acme-challenge.www.dev",
+		},
+		{
+			name:          "fqdn outside of the configured zone name",
+			whenFQDN:      "_acme-challenge.example.org.",
+			whenConfig:    zoneConfig{ZoneID: "zone-id", ZoneName: "example.com"},
+			thenErrorText: "challenge FQDN '_acme-challenge.example.org' is not in the configured zone 'example.com'",
+		},
+		{
+			name:          "fqdn outside of the configured zone id",
+			givenZones:    map[string]string{"example.com": "zone-id"},
+			whenFQDN:      "_acme-challenge.example.org.",
+			whenConfig:    zoneConfig{ZoneID: "zone-id"},
+			thenErrorText: "challenge FQDN '_acme-challenge.example.org' is not in the configured zone 'example.com' (zone-id)",
+		},
+		{
+			name:           "configured zone name not found",
+			givenZones:     map[string]string{"dev.example.com": "subzone-id"},
+			whenFQDN:       "_acme-challenge.www.dev.example.com.",
+			whenConfig:     zoneConfig{ZoneName: "example.com"},
+			whenShouldFind: true,
+			thenErrorText:  "configured zone 'example.com' not found",
+		},
+		{
+			name:       "configured zone id to clean up not found",
+			givenZones: map[string]string{"example.com": "zone-id"},
+			whenFQDN:   "_acme-challenge.www.example.com.",
+			whenConfig: zoneConfig{ZoneID: "other-zone-id"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dnsAPIMock := mocks.NewDNSAPI(t)
+			getZonesCalls := 0
+			dnsAPIMock.EXPECT().GetZones(mock.Anything, mock.Anything).RunAndReturn(
+				func(_ context.Context, name string) (dnsclient.ZoneReadList, error) {
+					getZonesCalls++
+					// the API filters zones by a partial name
+					var zones []dnsclient.ZoneRead
+					for zoneName, id := range tc.givenZones {
+						if len(zoneName) >= len(name) && zoneName[len(zoneName)-len(name):] == name {
+							zones = append(zones, dnsclient.ZoneRead{Id: toPTR(id), Properties: &dnsclient.Zone{ZoneName: toPTR(zoneName)}})
+						}
+					}
+					return dnsclient.ZoneReadList{Items: &zones}, nil
+				}).Maybe()
+			dnsAPIMock.EXPECT().GetZone(mock.Anything, mock.Anything).RunAndReturn(
+				func(_ context.Context, zoneId string) (dnsclient.ZoneRead, error) {
+					for zoneName, id := range tc.givenZones {
+						if id == zoneId {
+							return dnsclient.ZoneRead{Id: toPTR(id), Properties: &dnsclient.Zone{ZoneName: toPTR(zoneName)}}, nil
+						}
+					}
+					return dnsclient.ZoneRead{}, &clouddns.APIError{StatusCode: http.StatusNotFound}
+				}).Maybe()
+			resolver := NewResolver(testNamespace, nil, nil, nil, zap.NewNop(),
+				WithZoneCache(time.Minute, time.Minute)).(*ionosCloudDnsProviderResolver)
+			ch := &v1alpha1.ChallengeRequest{ResolvedFQDN: tc.whenFQDN, ResolvedZone: "example.com.", DNSName: tc.whenDNSName}
+
+			zone, err := resolver.findZone(context.Background(), ch, tc.whenConfig, "ns/secret", tc.whenShouldFind, dnsAPIMock)
+
+			if tc.thenErrorText != "" {
+				require.EqualError(t, err, tc.thenErrorText)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.thenZone, zone)
+			if zone.id != "" {
+				require.Equal(t, tc.thenRecordName, recordNameFromChallenge(ch, zone.name))
+			}
+			require.LessOrEqual(t, getZonesCalls, 1, "zones should be listed at most once")
+
+			_, err = resolver.findZone(context.Background(), ch, tc.whenConfig, "ns/secret", tc.whenShouldFind, dnsAPIMock)
+			require.NoError(t, err)
+			require.LessOrEqual(t, getZonesCalls, 1, "zones should be taken from the cache")
+		})
+	}
+}
+
+func TestCreateZone(t *testing.T) {
+	testCases := []struct {
+		name            string
+		whenConfig      zoneConfig
+		whenDNSName     string
+		whenCreateError error
+		givenStates     []dnsclient.ProvisioningState
+		thenCreated     string
+		thenZone        dnsZone
+		thenErrorText   string
+	}{
+		{
+			name:        "zone is created after the dns name",
+			whenDNSName: "*.pr-42.preview.example.com",
+			givenStates: []dnsclient.ProvisioningState{dnsclient.PROVISIONINGSTATE_PROVISIONING, dnsclient.PROVISIONINGSTATE_AVAILABLE},
+			thenCreated: "pr-42.preview.example.com",
+			thenZone:    dnsZone{id: "created-id", name: "pr-42.preview.example.com"},
+		},
+		{
+			name:        "configured zone name is created",
+			whenConfig:  zoneConfig{ZoneName: "preview.example.com"},
+			whenDNSName: "pr-42.preview.example.com",
+			givenStates: []dnsclient.ProvisioningState{dnsclient.PROVISIONINGSTATE_AVAILABLE},
+			thenCreated: "preview.example.com",
+			thenZone:    dnsZone{id: "created-id", name: "preview.example.com"},
+		},
+		{
+			name:          "zone outside of the allowed suffixes",
+			whenDNSName:   "pr-42.example.com",
+			thenErrorText: "zone 'pr-42.example.com' not found, and creating it is not allowed",
+		},
+		{
+			name:          "configured zone id is not created",
+			whenConfig:    zoneConfig{ZoneID: "zone-id"},
+			whenDNSName:   "pr-42.preview.example.com",
+			thenErrorText: "configured zone zone-id not found",
+		},
+		{
+			name:          "zone provisioning fails",
+			whenDNSName:   "pr-42.preview.example.com",
+			givenStates:   []dnsclient.ProvisioningState{dnsclient.PROVISIONINGSTATE_FAILED},
+			thenCreated:   "pr-42.preview.example.com",
+			thenErrorText: "zone created-id is FAILED",
+		},
+		{
+			name:            "zone created by a concurrent challenge",
+			whenDNSName:     "pr-42.preview.example.com",
+			whenCreateError: &clouddns.APIError{StatusCode: http.StatusConflict},
+			thenCreated:     "pr-42.preview.example.com",
+			thenZone:        dnsZone{id: "concurrent-id", name: "pr-42.preview.example.com"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dnsAPIMock := mocks.NewDNSAPI(t)
+			if tc.thenCreated != "" {
+				zone := *dnsclient.NewZone(tc.thenCreated)
+				zone.SetDescription(zoneCreatedDescription)
+				dnsAPIMock.EXPECT().CreateZone(mock.Anything, zone).Return(dnsclient.ZoneRead{Id: toPTR("created-id")},
+					tc.whenCreateError)
+			}
+			if tc.whenCreateError != nil {
+				dnsAPIMock.EXPECT().GetZones(mock.Anything, tc.thenCreated).Return(dnsclient.ZoneReadList{Items: &[]dnsclient.ZoneRead{{
+					Id: toPTR("concurrent-id"), Properties: &dnsclient.Zone{ZoneName: toPTR(tc.thenCreated)},
+				}}}, nil)
+			}
+			for _, state := range tc.givenStates {
+				dnsAPIMock.EXPECT().GetZone(mock.Anything, "created-id").Return(dnsclient.ZoneRead{
+					Id:       toPTR("created-id"),
+					Metadata: &dnsclient.MetadataWithStateNameservers{State: toPTR(state), Nameservers: &[]string{"ns-ic.ui-dns.com"}},
+				}, nil).Once()
+			}
+			resolver := NewResolver(testNamespace, nil, nil, nil, zap.NewNop(),
+				WithZoneCreation([]string{"preview.example.com."})).(*ionosCloudDnsProviderResolver)
+			resolver.recordPollInterval = time.Millisecond
+			ch := &v1alpha1.ChallengeRequest{DNSName: tc.whenDNSName, ResolvedFQDN: "_acme-challenge.pr-42.preview.example.com."}
+
+			zone, err := resolver.createZone(context.Background(), ch, tc.whenConfig, "ns/secret", dnsAPIMock)
+
+			if tc.thenErrorText != "" {
+				require.EqualError(t, err, tc.thenErrorText)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.thenZone, zone)
+		})
+	}
+}
+
+func TestDeleteCreatedZone(t *testing.T) {
+	record := func(name string, recordType dnsclient.RecordType, state dnsclient.ProvisioningState) dnsclient.RecordRead {
+		return dnsclient.RecordRead{
+			Properties: &dnsclient.Record{Name: toPTR(name), Type: toPTR(recordType)},
+			Metadata:   &dnsclient.MetadataWithStateFqdnZoneId{State: toPTR(state)},
+		}
+	}
+	testCases := []struct {
+		name             string
+		givenDescription string
+		givenRecords     []dnsclient.RecordRead
+		thenDeleted      bool
+	}{
+		{
+			name:             "empty created zone is deleted",
+			givenDescription: zoneCreatedDescription,
+			givenRecords: []dnsclient.RecordRead{
+				record("@", dnsclient.RECORDTYPE_NS, dnsclient.PROVISIONINGSTATE_AVAILABLE),
+				record("_acme-challenge", dnsclient.RECORDTYPE_TXT, dnsclient.PROVISIONINGSTATE_DESTROYING),
+			},
+			thenDeleted: true,
+		},
+		{
+			name:             "created zone with records is kept",
+			givenDescription: zoneCreatedDescription,
+			givenRecords: []dnsclient.RecordRead{
+				record("_acme-challenge", dnsclient.RECORDTYPE_TXT, dnsclient.PROVISIONINGSTATE_AVAILABLE),
+			},
+		},
+		{
+			name:             "other zone is kept",
+			givenDescription: "production",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dnsAPIMock := mocks.NewDNSAPI(t)
+			dnsAPIMock.EXPECT().GetZone(mock.Anything, "zone-id").Return(dnsclient.ZoneRead{
+				Id:         toPTR("zone-id"),
+				Properties: &dnsclient.Zone{ZoneName: toPTR("pr-42.preview.example.com"), Description: toPTR(tc.givenDescription)},
+			}, nil)
+			if tc.givenRecords != nil {
+				dnsAPIMock.EXPECT().ListRecords(mock.Anything, "zone-id", clouddns.RecordFilter{}).
+					Return(dnsclient.RecordReadList{Items: &tc.givenRecords}, nil)
+			}
+			if tc.thenDeleted {
+				dnsAPIMock.EXPECT().DeleteZone(mock.Anything, "zone-id").Return(nil)
+			}
+			resolver := NewResolver(testNamespace, nil, nil, nil, zap.NewNop()).(*ionosCloudDnsProviderResolver)
+
+			err := resolver.deleteCreatedZone(context.Background(), "ns/secret", dnsZone{id: "zone-id", name: "pr-42.preview.example.com"},
+				dnsAPIMock)
+
+			require.NoError(t, err)
+		})
+	}
+}
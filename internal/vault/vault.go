@@ -0,0 +1,266 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	DefaultAuthMount               = "kubernetes"
+	DefaultServiceAccountTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+)
+
+type VaultAPI interface {
+	ReadSecret(ctx context.Context, mount, path string, kvVersion int) (*Secret, error)
+}
+
+// Secret is the data of a KV secret. Version is the version of a KV v2 secret, and empty for KV v1.
+type Secret struct {
+	Data    map[string]string
+	Version string
+}
+
+// Config configures the access to Vault. The client authenticates using the Kubernetes auth method, with the
+// service account token of the webhook.
+type Config struct {
+	Address                 string
+	AuthMount               string
+	Role                    string
+	ServiceAccountTokenFile string
+	CACertFile              string
+}
+
+func CreateVaultAPI(config Config) (VaultAPI, error) {
+	if config.AuthMount == "" {
+		config.AuthMount = DefaultAuthMount
+	}
+	if config.ServiceAccountTokenFile == "" {
+		config.ServiceAccountTokenFile = DefaultServiceAccountTokenFile
+	}
+	httpClient := &http.Client{Timeout: 30 * time.Second}
+	if config.CACertFile != "" {
+		caCert, err := os.ReadFile(config.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Vault CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no certificates found in Vault CA certificate %s", config.CACertFile)
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+		httpClient.Transport = transport
+	}
+	return &APIClient{
+		config:     config,
+		httpClient: httpClient,
+		now:        time.Now,
+	}, nil
+}
+
+// APIClient reads KV secrets from Vault. The Vault token obtained at login is cached and renewed while its lease
+// allows, before logging in again.
+type APIClient struct {
+	config     Config
+	httpClient *http.Client
+	now        func() time.Time
+	// group shares a renewal or login between concurrent reads.
+	group singleflight.Group
+
+	mu             sync.Mutex
+	token          string
+	renewable      bool
+	leaseDuration  time.Duration
+	leaseExpiresAt time.Time
+}
+
+// response is the common envelope of the Vault API responses.
+type response struct {
+	RawData json.RawMessage `json:"data"`
+	Auth    *struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int    `json:"lease_duration"`
+		Renewable     bool   `json:"renewable"`
+	} `json:"auth"`
+	Errors []string `json:"errors"`
+}
+
+func (c *APIClient) ReadSecret(ctx context.Context, mount, path string, kvVersion int) (*Secret, error) {
+	secret, status, err := c.readSecret(ctx, mount, path, kvVersion)
+	if status == http.StatusForbidden {
+		// the cached token may have been revoked, retry once with a new token
+		c.resetToken()
+		secret, _, err = c.readSecret(ctx, mount, path, kvVersion)
+	}
+	return secret, err
+}
+
+func (c *APIClient) readSecret(ctx context.Context, mount, path string, kvVersion int) (*Secret, int, error) {
+	token, err := c.getToken(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	apiPath := "/v1/" + escapePath(mount) + "/" + escapePath(path)
+	if kvVersion == 2 {
+		apiPath = "/v1/" + escapePath(mount) + "/data/" + escapePath(path)
+	}
+	resp, status, err := c.do(ctx, http.MethodGet, apiPath, token, nil)
+	if err != nil {
+		return nil, status, fmt.Errorf("failed to read secret %s from Vault: %w", path, err)
+	}
+
+	var data map[string]any
+	secret := &Secret{Data: map[string]string{}}
+	if kvVersion == 2 {
+		var kv2 struct {
+			Data     map[string]any `json:"data"`
+			Metadata struct {
+				Version int `json:"version"`
+			} `json:"metadata"`
+		}
+		if err := json.Unmarshal(resp.RawData, &kv2); err != nil {
+			return nil, status, fmt.Errorf("unexpected response from Vault: %w", err)
+		}
+		data = kv2.Data
+		secret.Version = strconv.Itoa(kv2.Metadata.Version)
+	} else if err := json.Unmarshal(resp.RawData, &data); err != nil {
+		return nil, status, fmt.Errorf("unexpected response from Vault: %w", err)
+	}
+	for key, value := range data {
+		if s, ok := value.(string); ok {
+			secret.Data[key] = s
+		}
+	}
+	return secret, status, nil
+}
+
+// escapePath escapes the segments of a path of the Vault API.
+func escapePath(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// getToken returns the cached Vault token, renews it when less than a third of its lease is left, or logs in
+// again when it cannot be renewed. The lock is not held during the calls to Vault; concurrent reads share a single
+// renewal or login.
+func (c *APIClient) getToken(ctx context.Context) (string, error) {
+	if token, valid, _ := c.cachedToken(); valid {
+		return token, nil
+	}
+	result, err, _ := c.group.Do("token", func() (any, error) {
+		// the token may have been replaced while waiting for the group
+		token, valid, renewable := c.cachedToken()
+		if valid {
+			return token, nil
+		}
+		if renewable {
+			resp, _, err := c.do(ctx, http.MethodPost, "/v1/auth/token/renew-self", token, nil)
+			if err == nil && resp.Auth != nil {
+				return c.setToken(resp), nil
+			}
+		}
+		return c.login(ctx)
+	})
+	if err != nil {
+		return "", err
+	}
+	return result.(string), nil
+}
+
+// login logs in to Vault with the service account token and caches the Vault token.
+func (c *APIClient) login(ctx context.Context) (string, error) {
+	jwt, err := os.ReadFile(c.config.ServiceAccountTokenFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read service account token: %w", err)
+	}
+	body, err := json.Marshal(map[string]string{"role": c.config.Role, "jwt": strings.TrimSpace(string(jwt))})
+	if err != nil {
+		return "", err
+	}
+	resp, _, err := c.do(ctx, http.MethodPost, "/v1/auth/"+escapePath(c.config.AuthMount)+"/login", "", body)
+	if err != nil {
+		return "", fmt.Errorf("failed to login to Vault: %w", err)
+	}
+	if resp.Auth == nil || resp.Auth.ClientToken == "" {
+		return "", fmt.Errorf("unexpected response from Vault login")
+	}
+	return c.setToken(resp), nil
+}
+
+// cachedToken returns the cached token, whether it is valid without renewal, and whether it can be renewed.
+func (c *APIClient) cachedToken() (string, bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	valid := c.token != "" && now.Before(c.leaseExpiresAt.Add(-c.leaseDuration/3))
+	renewable := c.token != "" && c.renewable && now.Before(c.leaseExpiresAt)
+	return c.token, valid, renewable
+}
+
+func (c *APIClient) setToken(resp *response) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = resp.Auth.ClientToken
+	c.renewable = resp.Auth.Renewable
+	c.leaseDuration = time.Duration(resp.Auth.LeaseDuration) * time.Second
+	c.leaseExpiresAt = c.now().Add(c.leaseDuration)
+	return c.token
+}
+
+func (c *APIClient) resetToken() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = ""
+}
+
+func (c *APIClient) do(ctx context.Context, method, path, token string, body []byte) (*response, int, error) {
+	req, err := http.NewRequestWithContext(ctx, method,
+		strings.TrimSuffix(c.config.Address, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	httpResp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer httpResp.Body.Close()
+	raw, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, httpResp.StatusCode, err
+	}
+	var resp response
+	if httpResp.StatusCode != http.StatusOK {
+		if json.Unmarshal(raw, &resp) == nil && len(resp.Errors) > 0 {
+			return nil, httpResp.StatusCode, fmt.Errorf("unexpected status code: %d: %s", httpResp.StatusCode,
+				strings.Join(resp.Errors, ", "))
+		}
+		return nil, httpResp.StatusCode, fmt.Errorf("unexpected status code: %d", httpResp.StatusCode)
+	}
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return nil, httpResp.StatusCode, fmt.Errorf("unexpected response from Vault: %w", err)
+	}
+	return &resp, httpResp.StatusCode, nil
+}
@@ -0,0 +1,170 @@
+//go:build unit
+
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeVault is an HTTP stand-in for the Vault endpoints used by the client.
+type fakeVault struct {
+	logins     atomic.Int32
+	renewals   atomic.Int32
+	revoked    atomic.Bool
+	validToken atomic.Value
+	loginDelay time.Duration
+	notFound   atomic.Value
+}
+
+func (f *fakeVault) handler(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/auth/kubernetes/login", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		if body["role"] != "webhook" || body["jwt"] != "sa-token" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"errors":["invalid role or jwt"]}`))
+			return
+		}
+		time.Sleep(f.loginDelay)
+		token := "vault-token-" + string(rune('0'+f.logins.Add(1)))
+		f.validToken.Store(token)
+		f.revoked.Store(false)
+		_, _ = w.Write([]byte(`{"auth":{"client_token":"` + token + `","lease_duration":3600,"renewable":true}}`))
+	})
+	mux.HandleFunc("POST /v1/auth/token/renew-self", func(w http.ResponseWriter, r *http.Request) {
+		f.renewals.Add(1)
+		token := r.Header.Get("X-Vault-Token")
+		_, _ = w.Write([]byte(`{"auth":{"client_token":"` + token + `","lease_duration":3600,"renewable":true}}`))
+	})
+	authorized := func(w http.ResponseWriter, r *http.Request) bool {
+		if f.revoked.Load() || r.Header.Get("X-Vault-Token") != f.validToken.Load() {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return false
+		}
+		return true
+	}
+	mux.HandleFunc("GET /v1/secret/data/ionos", func(w http.ResponseWriter, r *http.Request) {
+		if authorized(w, r) {
+			_, _ = w.Write([]byte(`{"data":{"data":{"auth-token":"kv2-token","ttl":3},"metadata":{"version":7}}}`))
+		}
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		f.notFound.Store(r.URL.EscapedPath())
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("GET /v1/kv/ionos", func(w http.ResponseWriter, r *http.Request) {
+		if authorized(w, r) {
+			_, _ = w.Write([]byte(`{"data":{"username":"user","password":"pass"}}`))
+		}
+	})
+	return mux
+}
+
+func newTestClient(t *testing.T, fake *fakeVault) *APIClient {
+	server := httptest.NewServer(fake.handler(t))
+	t.Cleanup(server.Close)
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("sa-token\n"), 0o600))
+	api, err := CreateVaultAPI(Config{Address: server.URL, Role: "webhook", ServiceAccountTokenFile: tokenFile})
+	require.NoError(t, err)
+	return api.(*APIClient)
+}
+
+func TestReadSecret(t *testing.T) {
+	fake := &fakeVault{}
+	client := newTestClient(t, fake)
+
+	secret, err := client.ReadSecret(context.Background(), "secret", "ionos", 2)
+	require.NoError(t, err)
+	require.Equal(t, &Secret{Data: map[string]string{"auth-token": "kv2-token"}, Version: "7"}, secret)
+
+	secret, err = client.ReadSecret(context.Background(), "/kv/", "ionos", 1)
+	require.NoError(t, err)
+	require.Equal(t, &Secret{Data: map[string]string{"username": "user", "password": "pass"}}, secret)
+	require.Equal(t, int32(1), fake.logins.Load(), "the vault token should be reused")
+}
+
+func TestTokenRenewal(t *testing.T) {
+	fake := &fakeVault{}
+	client := newTestClient(t, fake)
+	now := time.Now()
+	client.now = func() time.Time { return now }
+
+	_, err := client.ReadSecret(context.Background(), "secret", "ionos", 2)
+	require.NoError(t, err)
+
+	client.now = func() time.Time { return now.Add(50 * time.Minute) }
+	_, err = client.ReadSecret(context.Background(), "secret", "ionos", 2)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), fake.renewals.Load())
+
+	client.now = func() time.Time { return now.Add(3 * time.Hour) }
+	_, err = client.ReadSecret(context.Background(), "secret", "ionos", 2)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), fake.logins.Load(), "an expired vault token should be replaced by a new login")
+}
+
+func TestRevokedTokenIsReplaced(t *testing.T) {
+	fake := &fakeVault{}
+	client := newTestClient(t, fake)
+
+	_, err := client.ReadSecret(context.Background(), "secret", "ionos", 2)
+	require.NoError(t, err)
+	fake.revoked.Store(true)
+	_, err = client.ReadSecret(context.Background(), "secret", "ionos", 2)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), fake.logins.Load())
+}
+
+func TestReadSecretErrors(t *testing.T) {
+	fake := &fakeVault{}
+	client := newTestClient(t, fake)
+
+	_, err := client.ReadSecret(context.Background(), "secret", "missing", 2)
+	require.ErrorContains(t, err, "failed to read secret missing from Vault: unexpected status code: 404")
+
+	client.config.Role = "other"
+	client.resetToken()
+	_, err = client.ReadSecret(context.Background(), "secret", "ionos", 2)
+	require.ErrorContains(t, err, "failed to login to Vault: unexpected status code: 400: invalid role or jwt")
+}
+
+func TestConcurrentReadsShareLogin(t *testing.T) {
+	fake := &fakeVault{loginDelay: 50 * time.Millisecond}
+	client := newTestClient(t, fake)
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.ReadSecret(context.Background(), "secret", "ionos", 2)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, int32(1), fake.logins.Load())
+}
+
+func TestReadSecretEscapesPath(t *testing.T) {
+	fake := &fakeVault{}
+	client := newTestClient(t, fake)
+
+	_, err := client.ReadSecret(context.Background(), "secret", "ionos?dns/a b", 2)
+	require.ErrorContains(t, err, "unexpected status code: 404")
+	require.Equal(t, "/v1/secret/data/ionos%3Fdns/a%20b", fake.notFound.Load())
+}